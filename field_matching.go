@@ -0,0 +1,99 @@
+package qfv
+
+import "strings"
+
+// FieldMatching controls how the field names of the input match the configured fields
+type FieldMatching string
+
+const (
+	MatchExact           FieldMatching = "exact"            // Names must match exactly, the default
+	MatchCaseInsensitive FieldMatching = "case_insensitive" // First_Name matches first_name
+	MatchNormalized      FieldMatching = "normalized"       // Case, underscores and hyphens are ignored, createdAt matches created_at
+)
+
+func (m FieldMatching) String() string {
+	return string(m)
+}
+
+// WithFieldMatching sets how the field names of the input match the configured
+// fields. The parsers always return the canonical configured name.
+func WithFieldMatching(m FieldMatching) ParserOption {
+	return func(s *fieldSet) {
+		s.matching = m
+	}
+}
+
+// equal reports whether the names match under the matching mode
+func (m FieldMatching) equal(a, b string) bool {
+	switch m {
+	case MatchCaseInsensitive:
+		return strings.EqualFold(a, b)
+	case MatchNormalized:
+		return normalizeFieldName(a) == normalizeFieldName(b)
+	default:
+		return a == b
+	}
+}
+
+// normalizeFieldName lowercases the name and removes its word separators,
+// so camelCase, snake_case and kebab-case spellings compare equal
+func normalizeFieldName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(name))
+}
+
+// lookupFolded resolves name through the configured names it matches under the
+// matching mode. A name matching different fields is ambiguous and does not resolve.
+func (s *fieldSet) lookupFolded(name string, roles []string) (string, fieldAlias, bool) {
+	var field string
+	var alias fieldAlias
+	var found bool
+
+	for _, configured := range s.candidates(name) {
+		f, a, ok := s.lookup(configured, roles)
+		switch {
+		case !ok:
+			continue
+		case found && f != field:
+			return "", fieldAlias{}, false
+		case !found || alias.deprecated:
+			field, alias, found = f, a, true // Prefer the canonical name and current aliases
+		}
+	}
+
+	return field, alias, found
+}
+
+// candidates returns the configured names matching name under the matching mode,
+// with the wildcards of patterns replaced by the keys of name
+func (s *fieldSet) candidates(name string) []string {
+	var names []string
+	add := func(configured string) {
+		if pathWildcards(configured) > 0 {
+			if keys, ok := matchPathFunc(configured, name, s.matching.equal); ok {
+				names = append(names, expandPath(configured, keys))
+			}
+		} else if s.matching.equal(configured, name) {
+			names = append(names, configured)
+		}
+	}
+
+	for f := range s.allowed {
+		add(f)
+	}
+
+	if s.registry != nil {
+		for f := range s.registry.fields {
+			add(f)
+		}
+		for a := range s.registry.aliases {
+			add(a)
+		}
+	}
+
+	return names
+}
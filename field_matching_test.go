@@ -0,0 +1,156 @@
+package qfv
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFieldMatching_equal(t *testing.T) {
+	tests := []struct {
+		name     string
+		matching FieldMatching
+		a, b     string
+		want     bool
+	}{
+		{"exact", MatchExact, "first_name", "first_name", true},
+		{"exact case", MatchExact, "first_name", "First_Name", false},
+		{"case insensitive", MatchCaseInsensitive, "first_name", "First_Name", true},
+		{"case insensitive camelCase", MatchCaseInsensitive, "first_name", "firstName", false},
+		{"normalized camelCase", MatchNormalized, "first_name", "firstName", true},
+		{"normalized kebab-case", MatchNormalized, "first_name", "First-Name", true},
+		{"normalized different", MatchNormalized, "first_name", "last_name", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.matching.equal(tt.a, tt.b); got != tt.want {
+				t.Errorf("equal(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithFieldMatching(t *testing.T) {
+	allowed := []string{"first_name", "created_at", "address.city", "labels.*"}
+
+	tests := []struct {
+		name      string
+		matching  FieldMatching
+		input     string
+		want      string
+		wantAlias string
+		wantOk    bool
+	}{
+		{name: "exact by default", input: "First_Name"},
+		{name: "exact still matches", matching: MatchCaseInsensitive, input: "first_name", want: "first_name", wantOk: true},
+		{name: "case insensitive", matching: MatchCaseInsensitive, input: "First_Name", want: "first_name", wantAlias: "First_Name", wantOk: true},
+		{name: "case insensitive rejects camelCase", matching: MatchCaseInsensitive, input: "createdAt"},
+		{name: "normalized camelCase", matching: MatchNormalized, input: "createdAt", want: "created_at", wantAlias: "createdAt", wantOk: true},
+		{name: "normalized path", matching: MatchNormalized, input: "Address.City", want: "address.city", wantAlias: "Address.City", wantOk: true},
+		{name: "wildcard keys keep their case", matching: MatchCaseInsensitive, input: "LABELS.Env", want: "labels.Env", wantAlias: "LABELS.Env", wantOk: true},
+		{name: "normalized unknown", matching: MatchNormalized, input: "lastName"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []ParserOption
+			if tt.matching != "" {
+				opts = append(opts, WithFieldMatching(tt.matching))
+			}
+
+			filterOpts := make([]FilterOption, len(opts))
+			sortOpts := make([]SortOption, len(opts))
+			fieldsOpts := make([]FieldsOption, len(opts))
+			for i, opt := range opts {
+				filterOpts[i], sortOpts[i], fieldsOpts[i] = opt, opt, opt
+			}
+
+			node, filterErr := NewFilterParser(allowed, filterOpts...).Parse(tt.input + " = 'x'")
+			sortNode, sortErr := NewSortParser(allowed, sortOpts...).Parse(tt.input + " ASC")
+			fieldsNode, fieldsErr := NewFieldsParser(allowed, fieldsOpts...).Parse(tt.input)
+
+			if !tt.wantOk {
+				if filterErr == nil || sortErr == nil || fieldsErr == nil {
+					t.Errorf("Parse() errors = %v, %v, %v, want errors", filterErr, sortErr, fieldsErr)
+				}
+				return
+			}
+
+			for _, err := range []error{filterErr, sortErr, fieldsErr} {
+				if err != nil {
+					t.Fatalf("Parse() error = %v", err)
+				}
+			}
+
+			id := node.(*BinaryOperatorNode).Left.(*IdentifierNode)
+			if id.Name != tt.want || id.Alias != tt.wantAlias {
+				t.Errorf("IdentifierNode = %s (%s), want %s (%s)", id.Name, id.Alias, tt.want, tt.wantAlias)
+			}
+
+			if f := sortNode.Fields[0]; f.Field != tt.want || f.Alias != tt.wantAlias {
+				t.Errorf("SortFieldNode = %s (%s), want %s (%s)", f.Field, f.Alias, tt.want, tt.wantAlias)
+			}
+
+			if !reflect.DeepEqual(fieldsNode.Fields, []string{tt.want}) {
+				t.Errorf("FieldsNode.Fields = %v, want [%s]", fieldsNode.Fields, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithFieldMatching_Registry(t *testing.T) {
+	registry, err := NewFieldRegistry(
+		FieldDef{Name: "created_at", Aliases: []string{"creationDate"}, DeprecatedAliases: []string{"creation_date"}},
+		FieldDef{Name: "email", Permissions: []string{"admin"}},
+		FieldDef{Name: "user_id"},
+		FieldDef{Name: "userId"},
+	)
+	if err != nil {
+		t.Fatalf("NewFieldRegistry() error = %v", err)
+	}
+
+	var deprecated []string
+	parser := NewSortParser(nil,
+		WithFieldRegistry(registry),
+		WithFieldMatching(MatchNormalized),
+		WithDeprecatedAliasHandler(func(alias, field string) { deprecated = append(deprecated, alias) }),
+	)
+
+	tests := []struct {
+		name   string
+		input  string
+		roles  []string
+		want   string
+		wantOk bool
+	}{
+		{name: "canonical and aliases fold together", input: "CreationDate", want: "created_at", wantOk: true},
+		{name: "permissions still apply", input: "EMAIL"},
+		{name: "permissions granted", input: "EMAIL", roles: []string{"admin"}, want: "email", wantOk: true},
+		{name: "exact match wins over ambiguity", input: "userId", want: "userId", wantOk: true},
+		{name: "ambiguous name", input: "USER_ID"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parser.ParseWithRoles(tt.input+" DESC", tt.roles...)
+			if !tt.wantOk {
+				if err == nil {
+					t.Errorf("ParseWithRoles() = %v, want error", got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ParseWithRoles() error = %v", err)
+			}
+
+			if got.Fields[0].Field != tt.want {
+				t.Errorf("Field = %s, want %s", got.Fields[0].Field, tt.want)
+			}
+		})
+	}
+
+	if len(deprecated) != 0 {
+		t.Errorf("deprecated aliases = %v, want none when a current name matches", deprecated)
+	}
+}
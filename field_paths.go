@@ -0,0 +1,100 @@
+package qfv
+
+import (
+	"strings"
+	"unicode"
+)
+
+// PathWildcard is the path segment matching any key of a map field (e.g. labels.*)
+const PathWildcard = "*"
+
+// FieldPath is the sequence of segments of a dotted field name (e.g. address.city)
+type FieldPath []string
+
+// ParseFieldPath splits a dotted field name into its segments
+func ParseFieldPath(name string) FieldPath {
+	return strings.Split(name, ".")
+}
+
+func (fp FieldPath) String() string {
+	return strings.Join(fp, ".")
+}
+
+// IsNested reports whether the path has more than one segment
+func (fp FieldPath) IsNested() bool {
+	return len(fp) > 1
+}
+
+// isPathSegment reports whether the segment is made of letters, digits and underscores
+func isPathSegment(segment string) bool {
+	if segment == "" {
+		return false
+	}
+
+	for _, r := range segment {
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// pathWildcards returns the number of wildcard segments of the dotted name
+func pathWildcards(name string) int {
+	var n int
+	for _, segment := range strings.Split(name, ".") {
+		if segment == PathWildcard {
+			n++
+		}
+	}
+
+	return n
+}
+
+// matchPath matches the dotted name against the pattern, returning the keys
+// matched by its wildcard segments (e.g. labels.* matches labels.env with [env])
+func matchPath(pattern, name string) ([]string, bool) {
+	return matchPathFunc(pattern, name, func(a, b string) bool { return a == b })
+}
+
+// matchPathFunc is like matchPath, comparing the other segments with equal
+func matchPathFunc(pattern, name string, equal func(a, b string) bool) ([]string, bool) {
+	patternSegments := strings.Split(pattern, ".")
+	nameSegments := strings.Split(name, ".")
+	if len(patternSegments) != len(nameSegments) {
+		return nil, false
+	}
+
+	var keys []string
+	for i, segment := range patternSegments {
+		switch {
+		case segment == PathWildcard:
+			if !isPathSegment(nameSegments[i]) {
+				return nil, false
+			}
+			keys = append(keys, nameSegments[i])
+		case !equal(segment, nameSegments[i]):
+			return nil, false
+		}
+	}
+
+	return keys, true
+}
+
+// expandPath replaces the wildcard segments of the pattern with the keys, in order
+func expandPath(pattern string, keys []string) string {
+	segments := strings.Split(pattern, ".")
+	for i, segment := range segments {
+		if segment == PathWildcard && len(keys) > 0 {
+			segments[i], keys = keys[0], keys[1:]
+		}
+	}
+
+	return strings.Join(segments, ".")
+}
+
+// isIdentRune reports whether ch may be part of an identifier, allowing dotted paths
+func isIdentRune(ch rune, i int) bool {
+	return ch == '_' || unicode.IsLetter(ch) || (i > 0 && (unicode.IsDigit(ch) || ch == '.'))
+}
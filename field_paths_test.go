@@ -0,0 +1,180 @@
+package qfv
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFieldPath(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		wantPath   FieldPath
+		wantNested bool
+	}{
+		{name: "simple", input: "name", wantPath: FieldPath{"name"}},
+		{name: "nested", input: "address.city", wantPath: FieldPath{"address", "city"}, wantNested: true},
+		{name: "deeply nested", input: "a.b.c", wantPath: FieldPath{"a", "b", "c"}, wantNested: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := ParseFieldPath(tt.input)
+			if !reflect.DeepEqual(path, tt.wantPath) {
+				t.Errorf("ParseFieldPath() = %v, want %v", path, tt.wantPath)
+			}
+			if path.IsNested() != tt.wantNested {
+				t.Errorf("IsNested() = %v, want %v", path.IsNested(), tt.wantNested)
+			}
+			if path.String() != tt.input {
+				t.Errorf("String() = %s, want %s", path, tt.input)
+			}
+		})
+	}
+}
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		pattern  string
+		input    string
+		wantKeys []string
+		wantOk   bool
+	}{
+		{name: "exact", pattern: "address.city", input: "address.city", wantOk: true},
+		{name: "different segment", pattern: "address.city", input: "address.zip"},
+		{name: "wildcard", pattern: "labels.*", input: "labels.env", wantKeys: []string{"env"}, wantOk: true},
+		{name: "wildcard in the middle", pattern: "items.*.price", input: "items.a1.price", wantKeys: []string{"a1"}, wantOk: true},
+		{name: "wildcard needs a key", pattern: "labels.*", input: "labels"},
+		{name: "wildcard matches one segment", pattern: "labels.*", input: "labels.a.b"},
+		{name: "wildcard rejects empty key", pattern: "labels.*", input: "labels."},
+		{name: "wildcard rejects invalid key", pattern: "labels.*", input: "labels.a-b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, ok := matchPath(tt.pattern, tt.input)
+			if ok != tt.wantOk || !reflect.DeepEqual(keys, tt.wantKeys) {
+				t.Errorf("matchPath() = %v, %v, want %v, %v", keys, ok, tt.wantKeys, tt.wantOk)
+			}
+		})
+	}
+
+	if got := expandPath("labels.*.value", []string{"env"}); got != "labels.env.value" {
+		t.Errorf("expandPath() = %s, want labels.env.value", got)
+	}
+}
+
+func TestNestedFields(t *testing.T) {
+	allowed := []string{"name", "address.city", "metadata.tier", "labels.*"}
+	registry, err := NewFieldRegistry(
+		FieldDef{Name: "secrets.*", Permissions: []string{"admin"}},
+		FieldDef{Name: "attributes.*", Aliases: []string{"attrs.*"}},
+	)
+	if err != nil {
+		t.Fatalf("NewFieldRegistry() error = %v", err)
+	}
+
+	t.Run("filter", func(t *testing.T) {
+		parser := NewFilterParser(allowed, WithFieldRegistry(registry))
+
+		tests := []struct {
+			input    string
+			want     string
+			wantPath []FieldPath
+			wantErr  bool
+		}{
+			{
+				input:    "address.city = 'Berlin' AND labels.env = 'prod'",
+				want:     "((address.city = 'Berlin') AND (labels.env = 'prod'))",
+				wantPath: []FieldPath{{"address", "city"}, {"labels", "env"}},
+			},
+			{
+				input:    "attrs.color = 'red' OR name = 'John'",
+				want:     "((attributes.color = 'red') OR (name = 'John'))",
+				wantPath: []FieldPath{{"attributes", "color"}, nil},
+			},
+			{input: "address.zip = '10115'", wantErr: true},
+			{input: "address = 'Berlin'", wantErr: true},
+			{input: "labels = 'prod'", wantErr: true},
+			{input: "labels.a.b = 'prod'", wantErr: true},
+			{input: "secrets.token = 'x'", wantErr: true},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.input, func(t *testing.T) {
+				node, err := parser.Parse(tt.input)
+				if tt.wantErr {
+					if err == nil {
+						t.Errorf("Parse() = %v, want error", node)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("Parse() error = %v", err)
+				}
+
+				if node.String() != tt.want {
+					t.Errorf("Parse() = %s, want %s", node, tt.want)
+				}
+
+				var paths []FieldPath
+				Inspect(node, func(n Node) bool {
+					if id, ok := n.(*IdentifierNode); ok {
+						paths = append(paths, id.Path)
+					}
+					return true
+				})
+				if !reflect.DeepEqual(paths, tt.wantPath) {
+					t.Errorf("Path = %v, want %v", paths, tt.wantPath)
+				}
+			})
+		}
+
+		if _, err := parser.ParseWithRoles("secrets.token = 'x'", "admin"); err != nil {
+			t.Errorf("ParseWithRoles() error = %v, want nil", err)
+		}
+	})
+
+	t.Run("sort", func(t *testing.T) {
+		parser := NewSortParser(allowed, WithFieldRegistry(registry))
+
+		got, err := parser.Parse("address.city ASC, attrs.size DESC, name ASC")
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+
+		want := SortNode{Fields: []SortFieldNode{
+			{Field: "address.city", Path: FieldPath{"address", "city"}, Direction: SortAsc},
+			{Field: "attributes.size", Alias: "attrs.size", Path: FieldPath{"attributes", "size"}, Direction: SortDesc},
+			{Field: "name", Direction: SortAsc},
+		}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Parse() = %v, want %v", got, want)
+		}
+
+		for _, input := range []string{"labels.* ASC", "labels ASC", "address.zip DESC"} {
+			if _, err := parser.Parse(input); err == nil {
+				t.Errorf("Parse(%q) error = nil, want error", input)
+			}
+		}
+	})
+
+	t.Run("fields", func(t *testing.T) {
+		parser := NewFieldsParser(allowed, WithFieldRegistry(registry))
+
+		got, err := parser.Parse("name, address.city, labels.env")
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+
+		want := []FieldPath{{"name"}, {"address", "city"}, {"labels", "env"}}
+		if !reflect.DeepEqual(got.Paths(), want) {
+			t.Errorf("Paths() = %v, want %v", got.Paths(), want)
+		}
+
+		if _, err := parser.Parse("labels.*"); err == nil {
+			t.Errorf("Parse() error = nil, want error")
+		}
+	})
+}
@@ -0,0 +1,431 @@
+package qfv
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FieldDef describes a field exposed by the parsers
+type FieldDef struct {
+	// Name is the canonical name, used in the AST. Nested fields use dotted paths
+	// (e.g. address.city) and map keys the * wildcard segment (e.g. labels.*).
+	Name string
+
+	// Aliases are alternative names resolving to the field (e.g. createdAt for created_at).
+	// Aliases of a wildcard field have the same number of wildcards (e.g. tags.* for labels.*).
+	Aliases []string
+
+	// DeprecatedAliases resolve like Aliases, but each use is reported to the
+	// handler set with WithDeprecatedAliasHandler
+	DeprecatedAliases []string
+
+	// Permissions lists the roles allowed to use the field, a caller needs any of them.
+	// A field without permissions is available to every caller.
+	Permissions []string
+
+	// DependsOn makes the field computed from other fields (e.g. full_name from
+	// first_name and last_name), which are loaded in its place (see FieldsNode.Required).
+	// Dependencies need not be exposed to callers and may be computed fields too,
+	// but must not lead back to the field.
+	DependsOn []string
+}
+
+// FieldRegistry holds the fields exposed by the parsers along with the permissions
+// they require, so the same parsers serve callers with different roles
+type FieldRegistry struct {
+	fields   map[string]FieldDef
+	aliases  map[string]fieldAlias
+	patterns []string // Fields and aliases with wildcards, in registration order
+}
+
+// fieldAlias is an alternative name of a field
+type fieldAlias struct {
+	field      string
+	deprecated bool
+}
+
+// NewFieldRegistry creates a registry with the given fields
+func NewFieldRegistry(defs ...FieldDef) (*FieldRegistry, error) {
+	r := &FieldRegistry{
+		fields:  make(map[string]FieldDef, len(defs)),
+		aliases: make(map[string]fieldAlias),
+	}
+
+	for _, def := range defs {
+		if err := r.Register(def); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// Register adds the field to the registry
+func (r *FieldRegistry) Register(def FieldDef) error {
+	if def.Name == "" {
+		return fmt.Errorf("field name is required")
+	}
+
+	if r.registered(def.Name) {
+		return fmt.Errorf("field %s is already registered", def.Name)
+	}
+
+	aliases := make(map[string]fieldAlias, len(def.Aliases)+len(def.DeprecatedAliases))
+	add := func(alias string, deprecated bool) error {
+		if _, exists := aliases[alias]; exists || alias == def.Name || r.registered(alias) {
+			return fmt.Errorf("field %s: alias %s is already registered", def.Name, alias)
+		}
+		if pathWildcards(alias) != pathWildcards(def.Name) {
+			return fmt.Errorf("field %s: alias %s must have the same wildcards as the field", def.Name, alias)
+		}
+		aliases[alias] = fieldAlias{field: def.Name, deprecated: deprecated}
+		return nil
+	}
+
+	for _, alias := range def.Aliases {
+		if err := add(alias, false); err != nil {
+			return err
+		}
+	}
+	for _, alias := range def.DeprecatedAliases {
+		if err := add(alias, true); err != nil {
+			return err
+		}
+	}
+
+	for _, dep := range def.DependsOn {
+		switch {
+		case dep == "" || dep == def.Name:
+			return fmt.Errorf("field %s: invalid dependency %q", def.Name, dep)
+		case pathWildcards(dep) > 0:
+			return fmt.Errorf("field %s: dependency %s must not have wildcards", def.Name, dep)
+		}
+	}
+
+	patterns := len(r.patterns)
+	r.fields[def.Name] = def
+	if pathWildcards(def.Name) > 0 {
+		r.patterns = append(r.patterns, def.Name)
+	}
+
+	for _, names := range [][]string{def.Aliases, def.DeprecatedAliases} {
+		for _, alias := range names {
+			r.aliases[alias] = aliases[alias]
+			if pathWildcards(alias) > 0 {
+				r.patterns = append(r.patterns, alias)
+			}
+		}
+	}
+
+	// Only a cycle through the new field can appear, the registry had none before
+	if cycle := r.cycle(def.Name, []string{def.Name}, make(map[string]any)); cycle != nil {
+		delete(r.fields, def.Name)
+		for alias := range aliases {
+			delete(r.aliases, alias)
+		}
+		r.patterns = r.patterns[:patterns]
+		return fmt.Errorf("field %s: dependency cycle %s", def.Name, strings.Join(cycle, " -> "))
+	}
+
+	return nil
+}
+
+// cycle returns the dependencies leading from the last field of the path back to
+// field, resolving aliases, or nil if there are none
+func (r *FieldRegistry) cycle(field string, path []string, visited map[string]any) []string {
+	def, _, _, _ := r.lookup(path[len(path)-1])
+	for _, dep := range def.DependsOn {
+		next, _, _, ok := r.lookup(dep)
+		if !ok {
+			continue // Stored field
+		}
+
+		if next.Name == field {
+			return append(path, dep)
+		}
+
+		if _, done := visited[next.Name]; done {
+			continue
+		}
+		visited[next.Name] = struct{}{}
+
+		if cycle := r.cycle(field, append(path, dep), visited); cycle != nil {
+			return cycle
+		}
+	}
+
+	return nil
+}
+
+// lookup returns the definition and the canonical name of the field for name,
+// which may be an alias or match a wildcard field (e.g. tags.env for labels.*)
+func (r *FieldRegistry) lookup(name string) (FieldDef, string, fieldAlias, bool) {
+	if def, ok := r.fields[name]; ok {
+		return def, name, fieldAlias{}, true
+	}
+
+	if alias, ok := r.aliases[name]; ok {
+		return r.fields[alias.field], alias.field, alias, true
+	}
+
+	for _, pattern := range r.patterns {
+		keys, ok := matchPath(pattern, name)
+		if !ok {
+			continue
+		}
+
+		if alias, ok := r.aliases[pattern]; ok {
+			return r.fields[alias.field], expandPath(alias.field, keys), alias, true
+		}
+
+		return r.fields[pattern], name, fieldAlias{}, true
+	}
+
+	return FieldDef{}, "", fieldAlias{}, false
+}
+
+// registered reports whether the name is a field or an alias of the registry
+func (r *FieldRegistry) registered(name string) bool {
+	_, isField := r.fields[name]
+	_, isAlias := r.aliases[name]
+	return isField || isAlias
+}
+
+// Fields returns the sorted names of the fields available to a caller with the roles
+func (r *FieldRegistry) Fields(roles ...string) []string {
+	names := make([]string, 0, len(r.fields))
+	for name, def := range r.fields {
+		if def.permits(roles) {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// permits reports whether a caller with the roles may use the field
+func (d FieldDef) permits(roles []string) bool {
+	if len(d.Permissions) == 0 {
+		return true
+	}
+
+	for _, perm := range d.Permissions {
+		for _, role := range roles {
+			if perm == role {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// fieldSet resolves the field names used in expressions, it is shared by every parser
+type fieldSet struct {
+	allowed      map[string]any // any because don't allocate memory for struct{}
+	names        []string       // Allowed fields, in order
+	patterns     []string       // Allowed fields with wildcards (e.g. labels.*)
+	registry     *FieldRegistry
+	matching     FieldMatching
+	onDeprecated func(alias, field string)
+}
+
+// newFieldSet creates a field set with fields available to every caller
+func newFieldSet(allowedFields []string) fieldSet {
+	s := fieldSet{allowed: make(map[string]any, len(allowedFields)), matching: MatchExact}
+
+	for _, f := range allowedFields {
+		if _, dup := s.allowed[f]; !dup {
+			s.names = append(s.names, f)
+		}
+		s.allowed[f] = struct{}{}
+		if pathWildcards(f) > 0 {
+			s.patterns = append(s.patterns, f)
+		}
+	}
+
+	return s
+}
+
+// resolve returns the canonical name of the field for name, which may be an alias,
+// if it is available to a caller with the roles. Fields hidden from the caller
+// resolve as unknown fields, not revealing they exist.
+func (s *fieldSet) resolve(name string, roles []string) (string, bool) {
+	if pathWildcards(name) > 0 {
+		return "", false // Wildcards only appear in the schema
+	}
+
+	field, alias, ok := s.lookup(name, roles)
+	if !ok && s.matching != MatchExact {
+		field, alias, ok = s.lookupFolded(name, roles)
+	}
+	if !ok {
+		return "", false
+	}
+
+	if alias.deprecated && s.onDeprecated != nil {
+		s.onDeprecated(name, field)
+	}
+
+	return field, true
+}
+
+// lookup returns the canonical name of the field named exactly name
+func (s *fieldSet) lookup(name string, roles []string) (string, fieldAlias, bool) {
+	if _, ok := s.allowed[name]; ok {
+		return name, fieldAlias{}, true
+	}
+
+	if s.registry != nil {
+		if def, field, alias, ok := s.registry.lookup(name); ok {
+			if !def.permits(roles) {
+				return "", fieldAlias{}, false
+			}
+
+			return field, alias, true
+		}
+	}
+
+	for _, pattern := range s.patterns {
+		if _, ok := matchPath(pattern, name); ok {
+			return name, fieldAlias{}, true
+		}
+	}
+
+	return "", fieldAlias{}, false
+}
+
+// known returns the canonical name of the field for name, whatever the roles of the
+// caller, to check the names configured on the parsers
+func (s *fieldSet) known(name string) (string, bool) {
+	field, ok := s.knownExact(name)
+	if ok || s.matching == MatchExact {
+		return field, ok
+	}
+
+	for _, configured := range s.candidates(name) {
+		f, ok := s.knownExact(configured)
+		switch {
+		case !ok:
+			continue
+		case field != "" && f != field:
+			return "", false // Ambiguous, like in lookupFolded
+		}
+		field = f
+	}
+
+	return field, field != ""
+}
+
+// knownExact returns the canonical name of the field named exactly name, whatever the roles
+func (s *fieldSet) knownExact(name string) (string, bool) {
+	if _, ok := s.allowed[name]; ok {
+		return name, true
+	}
+
+	if s.registry != nil {
+		if _, field, _, ok := s.registry.lookup(name); ok {
+			return field, true
+		}
+	}
+
+	for _, pattern := range s.patterns {
+		if _, ok := matchPath(pattern, name); ok {
+			return name, true
+		}
+	}
+
+	return "", false
+}
+
+// isObject reports whether name is the path of an object holding fields available
+// to a caller with the roles (e.g. author for author.id)
+func (s *fieldSet) isObject(name string, roles []string) bool {
+	for _, field := range append(s.available(roles), s.patterns...) {
+		if strings.HasPrefix(field, name+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// available returns the fields available to a caller with the roles: the allowed
+// fields in order, followed by the sorted fields of the registry. Wildcard fields
+// (e.g. labels.*) are left out, their keys are not known.
+func (s *fieldSet) available(roles []string) []string {
+	names := make([]string, 0, len(s.names))
+	seen := make(map[string]any, len(s.names))
+
+	add := func(name string) {
+		if _, dup := seen[name]; !dup && pathWildcards(name) == 0 {
+			seen[name] = struct{}{}
+			names = append(names, name)
+		}
+	}
+
+	for _, name := range s.names {
+		add(name)
+	}
+	if s.registry != nil {
+		for _, name := range s.registry.Fields(roles...) {
+			add(name)
+		}
+	}
+
+	return names
+}
+
+// ParserOption configures the fields of any parser, it can be passed to
+// NewFilterParser, NewSortParser and NewFieldsParser
+type ParserOption func(*fieldSet)
+
+func (o ParserOption) applyFilter(p *FilterParser) { o(&p.fields) }
+func (o ParserOption) applySort(p *SortParser)     { o(&p.fields) }
+func (o ParserOption) applyFields(p *FieldsParser) { o(&p.fields) }
+
+// WithFieldRegistry makes the fields of the registry available, in addition to the
+// allowed fields, to callers holding their permissions (see ParseWithRoles)
+func WithFieldRegistry(r *FieldRegistry) ParserOption {
+	return func(s *fieldSet) {
+		s.registry = r
+	}
+}
+
+// WithDeprecatedAliasHandler sets a function called each time the input uses a
+// deprecated alias of a field of the registry (e.g. to log a warning)
+func WithDeprecatedAliasHandler(fn func(alias, field string)) ParserOption {
+	return func(s *fieldSet) {
+		s.onDeprecated = fn
+	}
+}
+
+// fieldToken returns the field name of an identifier token. Words that are keywords
+// of the filter grammar (e.g. in, order) are field names too, outside filters.
+func fieldToken(tok Token) (string, bool) {
+	switch tok.Type {
+	case TokenQuotedIdentifier:
+		return tok.Value, true
+	case TokenString, TokenInt, TokenFloat, TokenIllegal, TokenEOF:
+		return "", false
+	}
+
+	for i, r := range tok.Value {
+		if !isIdentRune(r, i) {
+			return "", false
+		}
+	}
+
+	return tok.Value, tok.Value != ""
+}
+
+// illegalTokenMessage describes an illegal token found where a field was expected
+func illegalTokenMessage(tok Token) string {
+	if strings.HasPrefix(tok.Value, `"`) || strings.HasPrefix(tok.Value, "`") {
+		return "invalid quoted identifier"
+	}
+
+	return "illegal token"
+}
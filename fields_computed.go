@@ -0,0 +1,82 @@
+package qfv
+
+import (
+	"fmt"
+	"slices"
+)
+
+// WithAlwaysIncluded sets fields loaded for every request (e.g. id), they are
+// added to FieldsNode.Required but not to the fields returned to the client.
+// Aliases are resolved, and unknown fields make every parse fail.
+func WithAlwaysIncluded(fields ...string) FieldsOption {
+	return fieldsOption(func(p *FieldsParser) {
+		p.alwaysIncluded = slices.Clone(fields)
+	})
+}
+
+// resolveAlwaysIncluded replaces the fields always included by their canonical names
+func (p *FieldsParser) resolveAlwaysIncluded() error {
+	for i, name := range p.alwaysIncluded {
+		canonical, ok := p.fields.known(name)
+		if !ok {
+			return fmt.Errorf("always included field %s: unknown field", name)
+		}
+		p.alwaysIncluded[i] = canonical
+	}
+
+	return nil
+}
+
+// required returns the paths of the fields to load for the selection
+func (p *FieldsParser) required(node FieldsNode) []string {
+	var required []string
+	seen := make(map[string]any)
+
+	var add func(name string)
+	add = func(name string) {
+		deps := p.dependencies(name)
+		if len(deps) == 0 {
+			if _, dup := seen[name]; !dup {
+				seen[name] = struct{}{}
+				required = append(required, name)
+			}
+			return
+		}
+
+		for _, dep := range deps { // The registry rejects dependency cycles
+			add(dep)
+		}
+	}
+
+	for _, name := range p.alwaysIncluded {
+		add(name)
+	}
+	for _, path := range node.Paths() {
+		add(path.String())
+	}
+
+	return required
+}
+
+// dependencies returns the canonical names of the fields the field is computed from,
+// nil for stored fields
+func (p *FieldsParser) dependencies(name string) []string {
+	if p.fields.registry == nil {
+		return nil
+	}
+
+	def, _, _, ok := p.fields.registry.lookup(name)
+	if !ok || len(def.DependsOn) == 0 {
+		return nil
+	}
+
+	deps := make([]string, len(def.DependsOn))
+	for i, dep := range def.DependsOn {
+		deps[i] = dep
+		if _, canonical, _, ok := p.fields.registry.lookup(dep); ok {
+			deps[i] = canonical // Dependencies may be given by alias
+		}
+	}
+
+	return deps
+}
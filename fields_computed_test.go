@@ -0,0 +1,228 @@
+package qfv
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFieldsParser_Required(t *testing.T) {
+	registry, err := NewFieldRegistry(
+		FieldDef{Name: "full_name", DependsOn: []string{"first_name", "last_name"}},
+		FieldDef{Name: "display_name", DependsOn: []string{"full_name", "nickname"}},
+		FieldDef{Name: "created_at", Aliases: []string{"createdAt"}},
+		FieldDef{Name: "age", DependsOn: []string{"createdAt"}}, // Dependency given by alias
+		FieldDef{Name: "author.full_name", DependsOn: []string{"author.first_name", "author.last_name"}},
+		FieldDef{Name: "salary_band", DependsOn: []string{"salary"}, Permissions: []string{"hr"}},
+	)
+	if err != nil {
+		t.Fatalf("NewFieldRegistry() error = %v", err)
+	}
+
+	parser := NewFieldsParser([]string{"id", "email", "nickname", "author.id"},
+		WithFieldRegistry(registry),
+		WithAlwaysIncluded("id"),
+	)
+
+	tests := []struct {
+		name         string
+		input        string
+		roles        []string
+		wantFields   []string
+		wantRequired []string
+	}{
+		{
+			name:         "stored fields",
+			input:        "email",
+			wantFields:   []string{"email"},
+			wantRequired: []string{"id", "email"},
+		},
+		{
+			name:         "computed field",
+			input:        "full_name, email",
+			wantFields:   []string{"full_name", "email"},
+			wantRequired: []string{"id", "first_name", "last_name", "email"},
+		},
+		{
+			name:         "transitive dependencies",
+			input:        "display_name",
+			wantFields:   []string{"display_name"},
+			wantRequired: []string{"id", "first_name", "last_name", "nickname"},
+		},
+		{
+			name:         "computed field depending on a computed field",
+			input:        "nickname, display_name, full_name",
+			wantFields:   []string{"nickname", "display_name", "full_name"},
+			wantRequired: []string{"id", "nickname", "first_name", "last_name"},
+		},
+		{
+			name:         "dependency given by alias",
+			input:        "age",
+			wantFields:   []string{"age"},
+			wantRequired: []string{"id", "created_at"},
+		},
+		{
+			name:         "nested computed field",
+			input:        "author(id, full_name)",
+			wantFields:   []string{"author"},
+			wantRequired: []string{"id", "author.id", "author.first_name", "author.last_name"},
+		},
+		{
+			name:         "wildcard",
+			input:        "*,-email",
+			wantFields:   []string{"id", "nickname", "author.id", "age", "author.full_name", "created_at", "display_name", "full_name"},
+			wantRequired: []string{"id", "nickname", "author.id", "created_at", "author.first_name", "author.last_name", "first_name", "last_name"},
+		},
+		{
+			name:         "computed field restricted to a role",
+			input:        "salary_band",
+			roles:        []string{"hr"},
+			wantFields:   []string{"salary_band"},
+			wantRequired: []string{"id", "salary"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parser.ParseWithRoles(tt.input, tt.roles...)
+			if err != nil {
+				t.Fatalf("ParseWithRoles() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got.Fields, tt.wantFields) {
+				t.Errorf("Fields = %v, want %v", got.Fields, tt.wantFields)
+			}
+			if !reflect.DeepEqual(got.Required, tt.wantRequired) {
+				t.Errorf("Required = %v, want %v", got.Required, tt.wantRequired)
+			}
+		})
+	}
+}
+
+func TestFieldRegistry_DependsOn(t *testing.T) {
+	tests := []struct {
+		name    string
+		def     FieldDef
+		wantErr string
+	}{
+		{
+			name: "valid dependencies",
+			def:  FieldDef{Name: "full_name", DependsOn: []string{"first_name", "last_name"}},
+		},
+		{
+			name:    "empty dependency",
+			def:     FieldDef{Name: "full_name", DependsOn: []string{""}},
+			wantErr: `field full_name: invalid dependency ""`,
+		},
+		{
+			name:    "depends on itself",
+			def:     FieldDef{Name: "full_name", DependsOn: []string{"full_name"}},
+			wantErr: `field full_name: invalid dependency "full_name"`,
+		},
+		{
+			name:    "depends on its own alias",
+			def:     FieldDef{Name: "x", Aliases: []string{"y"}, DependsOn: []string{"y"}},
+			wantErr: "field x: dependency cycle x -> y",
+		},
+		{
+			name:    "wildcard dependency",
+			def:     FieldDef{Name: "label_count", DependsOn: []string{"labels.*"}},
+			wantErr: "field label_count: dependency labels.* must not have wildcards",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewFieldRegistry(tt.def)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("NewFieldRegistry() error = %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("NewFieldRegistry() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFieldRegistry_DependencyCycles(t *testing.T) {
+	tests := []struct {
+		name    string
+		defs    []FieldDef
+		wantErr string
+	}{
+		{
+			name: "no cycle",
+			defs: []FieldDef{
+				{Name: "full_name", DependsOn: []string{"first_name", "last_name"}},
+				{Name: "display_name", DependsOn: []string{"full_name", "nickname"}},
+			},
+		},
+		{
+			name: "two fields",
+			defs: []FieldDef{
+				{Name: "ping", DependsOn: []string{"pong"}},
+				{Name: "pong", DependsOn: []string{"ping", "id"}},
+			},
+			wantErr: "field pong: dependency cycle pong -> ping -> pong",
+		},
+		{
+			name: "through aliases",
+			defs: []FieldDef{
+				{Name: "a", DependsOn: []string{"bee"}},
+				{Name: "b", Aliases: []string{"bee"}, DependsOn: []string{"c"}},
+				{Name: "c", Aliases: []string{"sea"}, DependsOn: []string{"a"}},
+			},
+			wantErr: "field c: dependency cycle c -> a -> bee -> c",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewFieldRegistry(tt.defs...)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("NewFieldRegistry() error = %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("NewFieldRegistry() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+
+	registry, err := NewFieldRegistry(FieldDef{Name: "ping", DependsOn: []string{"pong"}})
+	if err != nil {
+		t.Fatalf("NewFieldRegistry() error = %v", err)
+	}
+	if err := registry.Register(FieldDef{Name: "pong", Aliases: []string{"pung"}, DependsOn: []string{"ping"}}); err == nil {
+		t.Fatalf("Register() expected a dependency cycle error")
+	}
+	if err := registry.Register(FieldDef{Name: "pong", Aliases: []string{"pung"}}); err != nil {
+		t.Errorf("Register() after a rejected field error = %v", err)
+	}
+}
+
+func TestFieldsParser_AlwaysIncluded(t *testing.T) {
+	registry, err := NewFieldRegistry(FieldDef{Name: "created_at", Aliases: []string{"createdAt"}})
+	if err != nil {
+		t.Fatalf("NewFieldRegistry() error = %v", err)
+	}
+
+	parser := NewFieldsParser([]string{"id", "name"}, WithFieldRegistry(registry), WithAlwaysIncluded("createdAt"))
+	got, err := parser.Parse("name")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if want := []string{"created_at", "name"}; !reflect.DeepEqual(got.Required, want) {
+		t.Errorf("Required = %v, want %v", got.Required, want)
+	}
+
+	parser = NewFieldsParser([]string{"id", "name"}, WithAlwaysIncluded("nonexistent"))
+	want := "always included field nonexistent: unknown field"
+	if _, err := parser.Parse("name"); err == nil || err.Error() != want {
+		t.Errorf("Parse() error = %v, want %s", err, want)
+	}
+}
@@ -3,69 +3,287 @@ package qfv
 import (
 	"fmt"
 	"strings"
+	"text/scanner"
 )
 
 type QFVFieldsError struct {
 	Field   string
 	Message string
+	Pos     scanner.Position // Position of the error in the input, if known
 }
 
 func (e *QFVFieldsError) Error() string {
+	var at string
+	if e.Pos.IsValid() {
+		at = fmt.Sprintf(" at %d:%d", e.Pos.Line, e.Pos.Column)
+	}
+
 	if e.Field != "" {
-		return fmt.Sprintf("error on field '%s': %s", e.Field, e.Message)
+		return fmt.Sprintf("error on field '%s'%s: %s", e.Field, at, e.Message)
 	}
 
-	return fmt.Sprintf("error: %s", e.Message)
+	return fmt.Sprintf("error%s: %s", at, e.Message)
 }
 
-// FieldsNode represents the fields part of the query
+// FieldsNode represents the fields part of the query, or the selection of the
+// fields of a related object (e.g. id,name in author(id,name))
 type FieldsNode struct {
-	Fields []string
+	Fields []string // Canonical names of the fields, relative to the selected object
+
+	// Aliases maps the canonical name of the fields requested under another name
+	// (e.g. an alias) to the name used in the input, it is nil when there are none
+	Aliases map[string]string
+
+	// Nested holds the selections of related objects (e.g. author(id,name)), keyed by
+	// their name in Fields, it is nil when there are none
+	Nested map[string]FieldsNode
+
+	// Required lists the paths of the fields to load to answer the request: the
+	// always-included fields, followed by the requested fields with computed fields
+	// replaced by their dependencies. It is only set on the top-level node.
+	Required []string
 }
 
 func (n FieldsNode) Type() NodeType {
 	return NodeTypeFieldList
 }
 
+// Paths returns the fields as paths, split on the dots of nested fields,
+// with the selected fields of related objects in place of the objects
+// (e.g. author.id and author.name for author(id,name))
+func (n FieldsNode) Paths() []FieldPath {
+	paths := make([]FieldPath, 0, len(n.Fields))
+	for _, f := range n.Fields {
+		nested, ok := n.Nested[f]
+		if !ok {
+			paths = append(paths, ParseFieldPath(f))
+			continue
+		}
+
+		for _, path := range nested.Paths() {
+			paths = append(paths, append(ParseFieldPath(f), path...))
+		}
+	}
+
+	return paths
+}
+
 // FieldsParser parses the query parameter for fields
 type FieldsParser struct {
-	allowedFieldsFields map[string]any // any because don't allocate memory for struct{}
+	fields         fieldSet
+	presets        map[string][]string // Named field lists, selected with @name
+	alwaysIncluded []string
+	err            error // Invalid configuration, returned by every parse
 }
 
+// FieldsOption configures a FieldsParser
+type FieldsOption interface {
+	applyFields(*FieldsParser)
+}
+
+// fieldsOption adapts a function to the FieldsOption interface
+type fieldsOption func(*FieldsParser)
+
+func (o fieldsOption) applyFields(p *FieldsParser) { o(p) }
+
 // NewFieldsParser creates a new parser with the allowed fields
-func NewFieldsParser(allowedFields []string) *FieldsParser {
-	fieldsFields := make(map[string]any, len(allowedFields))
+func NewFieldsParser(allowedFields []string, opts ...FieldsOption) *FieldsParser {
+	p := &FieldsParser{
+		fields: newFieldSet(allowedFields),
+	}
 
-	for _, f := range allowedFields {
-		fieldsFields[f] = struct{}{}
+	for _, opt := range opts {
+		opt.applyFields(p)
 	}
+	p.err = p.validate()
+
+	return p
+}
 
-	return &FieldsParser{
-		allowedFieldsFields: fieldsFields,
+// validate checks the presets and the fields always included, which must be known fields
+func (p *FieldsParser) validate() error {
+	if err := p.checkPresets(); err != nil {
+		return err
 	}
+
+	return p.resolveAlwaysIncluded()
 }
 
 // Parse parses the fields parameter
 func (p *FieldsParser) Parse(input string) (FieldsNode, error) {
+	return p.ParseWithRoles(input)
+}
+
+// ParseWithRoles parses the fields parameter for a caller with the given roles,
+// which restrict the fields of the registry (see WithFieldRegistry)
+func (p *FieldsParser) ParseWithRoles(input string, roles ...string) (FieldsNode, error) {
+	if p.err != nil {
+		return FieldsNode{}, p.err
+	}
+
 	if input == "" {
 		return FieldsNode{}, &QFVFieldsError{Message: "empty input expression"}
 	}
 
-	parts := strings.Split(input, ",")
-	fields := make([]string, 0, len(parts))
+	in := &fieldsInput{lexer: NewLexer(input), roles: roles}
+	in.lexer.Parse()
+
+	node, _, err := p.parseSelection(in, "")
+	if err != nil {
+		return FieldsNode{}, err
+	}
+
+	if tok := in.lexer.Next(); tok.Type != TokenEOF {
+		return FieldsNode{}, &QFVFieldsError{Message: "unexpected closing parenthesis", Pos: tok.Pos}
+	}
+
+	node.Required = p.required(node)
+	return node, nil
+}
+
+// fieldsInput holds the state of a fields parameter being parsed
+type fieldsInput struct {
+	lexer *Lexer
+	roles []string
+}
+
+// parseSelection parses the fields of a selection up to its closing parenthesis or the
+// end of the input. The prefix is the path of the selected object as written in the
+// input (e.g. author), empty at the top level. It returns the selection along with the
+// canonical path of the object, the fields being resolved by their dotted paths.
+func (p *FieldsParser) parseSelection(in *fieldsInput, prefix string) (FieldsNode, string, error) {
+	sel := &selection{}
+	var fields []selectedField // Fields of a nested object, added once its canonical path is known
+
+	for {
+		tok := in.lexer.Next()
+		if prefix == "" && tok.Type == TokenIllegal && isSelectionOperator(tok.Value) {
+			if err := p.parseSelectionOperator(in, sel, tok); err != nil {
+				return FieldsNode{}, "", err
+			}
+		} else {
+			name, ok := fieldToken(tok)
+			switch {
+			case ok:
+			case tok.Type == TokenComma, tok.Type == TokenRPAREN, tok.Type == TokenEOF:
+				return FieldsNode{}, "", &QFVFieldsError{Message: "empty field expression", Pos: tok.Pos}
+			case tok.Type == TokenIllegal && isSelectionOperator(tok.Value):
+				return FieldsNode{}, "", &QFVFieldsError{Field: tok.Value, Message: "wildcards, exclusions and presets are only allowed at the top level", Pos: tok.Pos}
+			case tok.Type == TokenIllegal:
+				return FieldsNode{}, "", &QFVFieldsError{Field: tok.Value, Message: illegalTokenMessage(tok), Pos: tok.Pos}
+			default:
+				return FieldsNode{}, "", &QFVFieldsError{Field: tok.Value, Message: "expected field", Pos: tok.Pos}
+			}
+
+			field := selectedField{name: name, path: name, pos: tok.Pos}
+			if prefix != "" {
+				field.path = prefix + "." + name
+			}
+
+			if in.lexer.Peek().Type == TokenLPAREN {
+				in.lexer.Next()
+
+				child, childPath, err := p.parseSelection(in, field.path)
+				if err != nil {
+					return FieldsNode{}, "", err
+				}
+				if closing := in.lexer.Next(); closing.Type != TokenRPAREN {
+					return FieldsNode{}, "", &QFVFieldsError{Field: field.path, Message: "missing closing parenthesis", Pos: closing.Pos}
+				}
 
-	for _, part := range parts {
-		part = strings.TrimSpace(part)
-		if part == "" {
-			return FieldsNode{}, &QFVFieldsError{Field: part, Message: "empty field expression"}
+				field.canonical, field.nested = childPath, &child
+			} else if field.canonical, ok = p.fields.resolve(field.path, in.roles); !ok {
+				return FieldsNode{}, "", &QFVFieldsError{Field: field.path, Message: "unknown field", Pos: tok.Pos}
+			}
+
+			if prefix != "" {
+				fields = append(fields, field)
+			} else if err := sel.addField("", field); err != nil {
+				return FieldsNode{}, "", err
+			}
 		}
 
-		if _, exists := p.allowedFieldsFields[part]; !exists {
-			return FieldsNode{}, &QFVFieldsError{Field: part, Message: "unknown field"}
+		switch next := in.lexer.Peek(); next.Type {
+		case TokenEOF, TokenRPAREN:
+			canonicalPrefix := objectPath(fields)
+			if prefix != "" && canonicalPrefix == "" {
+				return FieldsNode{}, "", &QFVFieldsError{Field: fields[0].path, Message: "unknown field", Pos: fields[0].pos}
+			}
+			for _, field := range fields {
+				if err := sel.addField(canonicalPrefix, field); err != nil {
+					return FieldsNode{}, "", err
+				}
+			}
+
+			node, err := sel.node()
+			return node, canonicalPrefix, err
+		case TokenComma:
+			in.lexer.Next()
+		default:
+			return FieldsNode{}, "", &QFVFieldsError{Field: next.Value, Message: "expected comma after field", Pos: next.Pos}
 		}
+	}
+}
+
+// selectedField is a field of a selection, resolved to its canonical path
+type selectedField struct {
+	name      string      // Name as written in the selection (e.g. name)
+	path      string      // Path as written in the input (e.g. author.name)
+	canonical string      // Canonical path of the field or the related object
+	nested    *FieldsNode // Selection of the related object, nil for fields
+	pos       scanner.Position
+}
+
+// objectPath returns the canonical path of the object owning the fields. Aliases
+// may rename the object (e.g. tags for metadata.labels) or move a field deeper
+// (e.g. author.mail for author.contact.email), so the path is the shortest one
+// found by removing the written name from a canonical path that holds every field.
+// When there is none, the path found from the first field is returned.
+func objectPath(fields []selectedField) string {
+	var path, first string
+	for _, field := range fields {
+		candidate := trimPath(field.canonical, len(ParseFieldPath(field.name)))
+		if first == "" {
+			first = candidate
+		}
+		if candidate == "" || (path != "" && len(ParseFieldPath(candidate)) >= len(ParseFieldPath(path))) {
+			continue
+		}
+
+		holdsAll := true
+		for _, other := range fields {
+			if _, ok := relativePath(candidate, other.canonical); !ok {
+				holdsAll = false
+				break
+			}
+		}
+		if holdsAll {
+			path = candidate
+		}
+	}
+
+	if path == "" {
+		return first
+	}
+
+	return path
+}
+
+// trimPath removes the last n segments of the dotted path
+func trimPath(path string, n int) string {
+	segments := ParseFieldPath(path)
+	if n >= len(segments) {
+		return ""
+	}
+
+	return segments[:len(segments)-n].String()
+}
 
-		fields = append(fields, part)
+// relativePath returns the dotted path relative to the prefix path
+func relativePath(prefix, path string) (string, bool) {
+	if prefix == "" {
+		return path, true
 	}
 
-	return FieldsNode{Fields: fields}, nil
+	return strings.CutPrefix(path, prefix+".")
 }
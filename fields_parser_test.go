@@ -3,6 +3,7 @@ package qfv
 import (
 	"reflect"
 	"testing"
+	"text/scanner"
 )
 
 func TestFieldsParser_Parse(t *testing.T) {
@@ -25,7 +26,8 @@ func TestFieldsParser_Parse(t *testing.T) {
 			name:  "single field",
 			input: "name",
 			expected: FieldsNode{
-				Fields: []string{"name"},
+				Fields:   []string{"name"},
+				Required: []string{"name"},
 			},
 			expectedErr: nil,
 		},
@@ -33,13 +35,14 @@ func TestFieldsParser_Parse(t *testing.T) {
 			name:        "single field, extra comma",
 			input:       "name,",
 			expected:    FieldsNode{},
-			expectedErr: &QFVFieldsError{Field: "", Message: "empty field expression"},
+			expectedErr: &QFVFieldsError{Message: "empty field expression", Pos: scanner.Position{Line: 1, Column: 6}},
 		},
 		{
 			name:  "multiple fields",
 			input: "name,age,city",
 			expected: FieldsNode{
-				Fields: []string{"name", "age", "city"},
+				Fields:   []string{"name", "age", "city"},
+				Required: []string{"name", "age", "city"},
 			},
 			expectedErr: nil,
 		},
@@ -47,7 +50,8 @@ func TestFieldsParser_Parse(t *testing.T) {
 			name:  "multiple fields with extra spaces",
 			input: "name,   age, city",
 			expected: FieldsNode{
-				Fields: []string{"name", "age", "city"},
+				Fields:   []string{"name", "age", "city"},
+				Required: []string{"name", "age", "city"},
 			},
 			expectedErr: nil,
 		},
@@ -55,25 +59,37 @@ func TestFieldsParser_Parse(t *testing.T) {
 			name:        "invalid field",
 			input:       "unknown",
 			expected:    FieldsNode{},
-			expectedErr: &QFVFieldsError{Field: "unknown", Message: "unknown field"},
+			expectedErr: &QFVFieldsError{Field: "unknown", Message: "unknown field", Pos: scanner.Position{Line: 1, Column: 1}},
 		},
 		{
 			name:        "invalid field uppercase",
 			input:       "NAME",
 			expected:    FieldsNode{},
-			expectedErr: &QFVFieldsError{Field: "NAME", Message: "unknown field"},
+			expectedErr: &QFVFieldsError{Field: "NAME", Message: "unknown field", Pos: scanner.Position{Line: 1, Column: 1}},
 		},
 		{
 			name:        "empty part",
 			input:       "name, ,age",
 			expected:    FieldsNode{},
-			expectedErr: &QFVFieldsError{Field: "", Message: "empty field expression"},
+			expectedErr: &QFVFieldsError{Message: "empty field expression", Pos: scanner.Position{Line: 1, Column: 7}},
 		},
 		{
 			name:        "single comma",
 			input:       ",",
 			expected:    FieldsNode{},
-			expectedErr: &QFVFieldsError{Field: "", Message: "empty field expression"},
+			expectedErr: &QFVFieldsError{Message: "empty field expression", Pos: scanner.Position{Line: 1, Column: 1}},
+		},
+		{
+			name:        "missing comma",
+			input:       "name age",
+			expected:    FieldsNode{},
+			expectedErr: &QFVFieldsError{Field: "age", Message: "expected comma after field", Pos: scanner.Position{Line: 1, Column: 6}},
+		},
+		{
+			name:        "unexpected character",
+			input:       "name, age!",
+			expected:    FieldsNode{},
+			expectedErr: &QFVFieldsError{Field: "!", Message: "expected comma after field", Pos: scanner.Position{Line: 1, Column: 10}},
 		},
 	}
 
@@ -101,3 +117,230 @@ func TestFieldsParser_Parse(t *testing.T) {
 		})
 	}
 }
+
+func TestFieldsParser_QuotedIdentifiers(t *testing.T) {
+	parser := NewFieldsParser([]string{"order", "first-name", "say \"hi\"", "name"})
+
+	tests := []struct {
+		name        string
+		input       string
+		expected    FieldsNode
+		expectedErr error
+	}{
+		{
+			name:     "quoted fields",
+			input:    "\"first-name\", `order`, name",
+			expected: FieldsNode{Fields: []string{"first-name", "order", "name"}, Required: []string{"first-name", "order", "name"}},
+		},
+		{
+			name:     "doubled quotes",
+			input:    `"say ""hi"""`,
+			expected: FieldsNode{Fields: []string{`say "hi"`}, Required: []string{`say "hi"`}},
+		},
+		{
+			name:        "unterminated quote",
+			input:       "`order, name",
+			expectedErr: &QFVFieldsError{Field: "`order, name", Message: "invalid quoted identifier", Pos: scanner.Position{Line: 1, Column: 1}},
+		},
+		{
+			name:        "empty quoted identifier",
+			input:       `name, "", age`,
+			expectedErr: &QFVFieldsError{Field: `"`, Message: "invalid quoted identifier", Pos: scanner.Position{Line: 1, Column: 7}},
+		},
+		{
+			name:     "keywords as field names",
+			input:    "order, name",
+			expected: FieldsNode{Fields: []string{"order", "name"}, Required: []string{"order", "name"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := parser.Parse(tt.input)
+			if tt.expectedErr != nil {
+				if err == nil || err.Error() != tt.expectedErr.Error() {
+					t.Fatalf("expected error '%v', got '%v'", tt.expectedErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("expected '%v', got '%v'", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFieldsParser_Nested(t *testing.T) {
+	registry, err := NewFieldRegistry(
+		FieldDef{Name: "author.email", Permissions: []string{"admin"}},
+		FieldDef{Name: "metadata.labels.*", Aliases: []string{"tags.*"}},
+		FieldDef{Name: "author.contact.email", Aliases: []string{"author.mail"}},
+	)
+	if err != nil {
+		t.Fatalf("NewFieldRegistry() error = %v", err)
+	}
+
+	parser := NewFieldsParser([]string{
+		"id", "name", "author.id", "author.name", "author.address.city", "comments.body", "comments.author.name",
+	}, WithFieldRegistry(registry))
+
+	tests := []struct {
+		name        string
+		input       string
+		roles       []string
+		expected    FieldsNode
+		paths       []string
+		expectedErr error
+	}{
+		{
+			name:  "nested selections",
+			input: "id,name,author(id,name),comments(body)",
+			expected: FieldsNode{
+				Fields: []string{"id", "name", "author", "comments"},
+				Nested: map[string]FieldsNode{
+					"author":   {Fields: []string{"id", "name"}},
+					"comments": {Fields: []string{"body"}},
+				},
+				Required: []string{"id", "name", "author.id", "author.name", "comments.body"},
+			},
+			paths: []string{"id", "name", "author.id", "author.name", "comments.body"},
+		},
+		{
+			name:  "deeply nested selections",
+			input: "comments(author(name), body), author(address.city)",
+			expected: FieldsNode{
+				Fields: []string{"comments", "author"},
+				Nested: map[string]FieldsNode{
+					"comments": {
+						Fields: []string{"author", "body"},
+						Nested: map[string]FieldsNode{"author": {Fields: []string{"name"}}},
+					},
+					"author": {Fields: []string{"address.city"}},
+				},
+				Required: []string{"comments.author.name", "comments.body", "author.address.city"},
+			},
+			paths: []string{"comments.author.name", "comments.body", "author.address.city"},
+		},
+		{
+			name:  "dotted and nested selections",
+			input: "author.id, author(name)",
+			expected: FieldsNode{
+				Fields:   []string{"author.id", "author"},
+				Nested:   map[string]FieldsNode{"author": {Fields: []string{"name"}}},
+				Required: []string{"author.id", "author.name"},
+			},
+			paths: []string{"author.id", "author.name"},
+		},
+		{
+			name:  "aliased object",
+			input: "tags(env, team)",
+			expected: FieldsNode{
+				Fields:   []string{"metadata.labels"},
+				Aliases:  map[string]string{"metadata.labels": "tags"},
+				Nested:   map[string]FieldsNode{"metadata.labels": {Fields: []string{"env", "team"}}},
+				Required: []string{"metadata.labels.env", "metadata.labels.team"},
+			},
+			paths: []string{"metadata.labels.env", "metadata.labels.team"},
+		},
+		{
+			name:  "alias moving a nested field deeper",
+			input: "author(id, mail)",
+			expected: FieldsNode{
+				Fields: []string{"author"},
+				Nested: map[string]FieldsNode{"author": {
+					Fields:  []string{"id", "contact.email"},
+					Aliases: map[string]string{"contact.email": "mail"},
+				}},
+				Required: []string{"author.id", "author.contact.email"},
+			},
+			paths: []string{"author.id", "author.contact.email"},
+		},
+		{
+			name:  "alias moving a nested field deeper first",
+			input: "author(mail, id)",
+			expected: FieldsNode{
+				Fields: []string{"author"},
+				Nested: map[string]FieldsNode{"author": {
+					Fields:  []string{"contact.email", "id"},
+					Aliases: map[string]string{"contact.email": "mail"},
+				}},
+				Required: []string{"author.contact.email", "author.id"},
+			},
+			paths: []string{"author.contact.email", "author.id"},
+		},
+		{
+			name:  "nested field with role",
+			input: "author(email)",
+			roles: []string{"admin"},
+			expected: FieldsNode{
+				Fields:   []string{"author"},
+				Nested:   map[string]FieldsNode{"author": {Fields: []string{"email"}}},
+				Required: []string{"author.email"},
+			},
+			paths: []string{"author.email"},
+		},
+		{
+			name:        "nested field without role",
+			input:       "id, author(email)",
+			expectedErr: &QFVFieldsError{Field: "author.email", Message: "unknown field", Pos: scanner.Position{Line: 1, Column: 12}},
+		},
+		{
+			name:        "unknown nested field",
+			input:       "author(id, password)",
+			expectedErr: &QFVFieldsError{Field: "author.password", Message: "unknown field", Pos: scanner.Position{Line: 1, Column: 12}},
+		},
+		{
+			name:        "empty selection",
+			input:       "author()",
+			expectedErr: &QFVFieldsError{Message: "empty field expression", Pos: scanner.Position{Line: 1, Column: 8}},
+		},
+		{
+			name:        "missing closing parenthesis",
+			input:       "author(id, name",
+			expectedErr: &QFVFieldsError{Field: "author", Message: "missing closing parenthesis", Pos: scanner.Position{Line: 1, Column: 16}},
+		},
+		{
+			name:        "unexpected closing parenthesis",
+			input:       "author(id), name)",
+			expectedErr: &QFVFieldsError{Message: "unexpected closing parenthesis", Pos: scanner.Position{Line: 1, Column: 17}},
+		},
+		{
+			name:        "duplicate selection",
+			input:       "author(id), author(name)",
+			expectedErr: &QFVFieldsError{Field: "author", Message: "duplicate field selection", Pos: scanner.Position{Line: 1, Column: 13}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := parser.ParseWithRoles(tt.input, tt.roles...)
+			if tt.expectedErr != nil {
+				if err == nil || err.Error() != tt.expectedErr.Error() {
+					t.Fatalf("expected error '%v', got '%v'", tt.expectedErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("expected '%v', got '%v'", tt.expected, actual)
+			}
+
+			var paths []string
+			for _, path := range actual.Paths() {
+				paths = append(paths, path.String())
+			}
+			if !reflect.DeepEqual(paths, tt.paths) {
+				t.Errorf("Paths() = %v, want %v", paths, tt.paths)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package qfv
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// WithFieldPreset defines a named list of fields, selected with @name in the fields
+// parameter (e.g. @summary). Fields not available to the caller are left out, and
+// unknown fields make every parse fail.
+func WithFieldPreset(name string, fields ...string) FieldsOption {
+	return fieldsOption(func(p *FieldsParser) {
+		if p.presets == nil {
+			p.presets = make(map[string][]string)
+		}
+		p.presets[name] = slices.Clone(fields)
+	})
+}
+
+// checkPresets returns an error if a preset has unknown fields
+func (p *FieldsParser) checkPresets() error {
+	names := slices.Sorted(maps.Keys(p.presets))
+	for _, name := range names {
+		for _, field := range p.presets[name] {
+			if _, ok := p.fields.known(field); !ok {
+				return fmt.Errorf("preset %s: unknown field %s", name, field)
+			}
+		}
+	}
+
+	return nil
+}
+
+// isSelectionOperator reports whether the character starts a wildcard (*),
+// an exclusion (-field) or a preset (@name) in the fields parameter
+func isSelectionOperator(s string) bool {
+	return s == "*" || s == "-" || s == "@"
+}
+
+// parseSelectionOperator parses a wildcard, an exclusion or a preset, tok being its
+// first character, adding the fields to the selection
+func (p *FieldsParser) parseSelectionOperator(in *fieldsInput, sel *selection, tok Token) error {
+	if tok.Value == "*" {
+		for _, name := range p.fields.available(in.roles) {
+			sel.add(name, name)
+		}
+		return nil
+	}
+
+	next := in.lexer.Peek()
+	name, ok := fieldToken(next)
+	if !ok || next.Pos.Offset != tok.Pos.Offset+1 {
+		if tok.Value == "-" {
+			return &QFVFieldsError{Field: tok.Value, Message: "missing field after exclusion", Pos: tok.Pos}
+		}
+		return &QFVFieldsError{Field: tok.Value, Message: "missing preset name", Pos: tok.Pos}
+	}
+	in.lexer.Next()
+
+	if tok.Value == "-" {
+		canonical, ok := p.fields.resolve(name, in.roles)
+		if !ok && p.fields.isObject(name, in.roles) {
+			canonical, ok = name, true // Excludes the object and all its fields
+		}
+		if !ok {
+			return &QFVFieldsError{Field: name, Message: "cannot exclude unknown field", Pos: next.Pos}
+		}
+		sel.exclude(canonical)
+		return nil
+	}
+
+	preset, ok := p.presets[name]
+	if !ok {
+		return &QFVFieldsError{Field: name, Message: "unknown field preset", Pos: next.Pos}
+	}
+
+	for _, field := range preset {
+		if canonical, ok := p.fields.resolve(field, in.roles); ok {
+			sel.add(canonical, canonical)
+		}
+	}
+
+	return nil
+}
+
+// selection collects the fields of a selection, without duplicates
+type selection struct {
+	fields   []string
+	seen     map[string]any
+	aliases  map[string]string
+	nested   map[string]FieldsNode
+	excluded []string
+}
+
+// add adds the field, with its canonical name and the name used in the input
+func (s *selection) add(canonical, name string) {
+	if _, dup := s.seen[canonical]; dup {
+		return
+	}
+	if s.seen == nil {
+		s.seen = make(map[string]any)
+	}
+	s.seen[canonical] = struct{}{}
+	s.fields = append(s.fields, canonical)
+
+	if canonical != name {
+		if s.aliases == nil {
+			s.aliases = make(map[string]string)
+		}
+		s.aliases[canonical] = name
+	}
+}
+
+// addNested records the selection of a related object, returning false if the
+// object was already selected
+func (s *selection) addNested(canonical, name string, node FieldsNode) bool {
+	if _, dup := s.nested[canonical]; dup {
+		return false
+	}
+	if s.nested == nil {
+		s.nested = make(map[string]FieldsNode)
+	}
+	s.nested[canonical] = node
+
+	return true
+}
+
+// addField adds the parsed field, or related object, relative to the canonical
+// path of the selected object
+func (s *selection) addField(prefix string, f selectedField) error {
+	relative, ok := relativePath(prefix, f.canonical)
+	if !ok || relative == "" {
+		return &QFVFieldsError{Field: f.path, Message: "unknown field", Pos: f.pos}
+	}
+
+	if f.nested != nil && !s.addNested(relative, f.name, *f.nested) {
+		return &QFVFieldsError{Field: f.path, Message: "duplicate field selection", Pos: f.pos}
+	}
+	s.add(relative, f.name)
+
+	return nil
+}
+
+// exclude removes the field from the selection, wherever it appears in the input
+func (s *selection) exclude(canonical string) {
+	s.excluded = append(s.excluded, canonical)
+}
+
+// node returns the selected fields without the excluded ones, and without the
+// fields also selected through a related object (e.g. author.id with author(id))
+func (s *selection) node() (FieldsNode, error) {
+	covered := make(map[string]any)
+	for object, nested := range s.nested {
+		for _, path := range nested.Paths() {
+			covered[object+"."+path.String()] = struct{}{}
+		}
+	}
+
+	node := FieldsNode{Fields: s.fields, Aliases: s.aliases, Nested: s.nested}
+	node.Fields = slices.DeleteFunc(node.Fields, func(f string) bool {
+		_, dup := covered[f]
+		if dup || s.excludes(f) {
+			delete(node.Aliases, f)
+			delete(node.Nested, f)
+			return true
+		}
+		return false
+	})
+
+	if len(node.Aliases) == 0 {
+		node.Aliases = nil
+	}
+	if len(node.Nested) == 0 {
+		node.Nested = nil
+	}
+	if len(node.Fields) == 0 {
+		if len(s.excluded) > 0 {
+			return FieldsNode{}, &QFVFieldsError{Message: "all selected fields are excluded"}
+		}
+		return FieldsNode{}, &QFVFieldsError{Message: "no fields selected"}
+	}
+
+	return node, nil
+}
+
+// excludes reports whether the field, or the object holding it, is excluded
+func (s *selection) excludes(field string) bool {
+	for _, excluded := range s.excluded {
+		if field == excluded || strings.HasPrefix(field, excluded+".") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,162 @@
+package qfv
+
+import (
+	"reflect"
+	"testing"
+	"text/scanner"
+)
+
+func TestFieldsParser_Selection(t *testing.T) {
+	registry, err := NewFieldRegistry(
+		FieldDef{Name: "created_at", Aliases: []string{"createdAt"}},
+		FieldDef{Name: "password_hash", Permissions: []string{"admin"}},
+		FieldDef{Name: "labels.*"},
+	)
+	if err != nil {
+		t.Fatalf("NewFieldRegistry() error = %v", err)
+	}
+
+	parser := NewFieldsParser([]string{"id", "name", "email", "author.id"},
+		WithFieldRegistry(registry),
+		WithFieldPreset("summary", "id", "name", "createdAt"),
+		WithFieldPreset("audit", "id", "password_hash"),
+		WithFieldPreset("secrets", "password_hash"),
+	)
+
+	tests := []struct {
+		name        string
+		input       string
+		roles       []string
+		expected    FieldsNode
+		expectedErr error
+	}{
+		{
+			name:     "wildcard",
+			input:    "*",
+			expected: FieldsNode{Fields: []string{"id", "name", "email", "author.id", "created_at"}, Required: []string{"id", "name", "email", "author.id", "created_at"}},
+		},
+		{
+			name:     "wildcard with role",
+			input:    "*",
+			roles:    []string{"admin"},
+			expected: FieldsNode{Fields: []string{"id", "name", "email", "author.id", "created_at", "password_hash"}, Required: []string{"id", "name", "email", "author.id", "created_at", "password_hash"}},
+		},
+		{
+			name:     "wildcard with exclusions",
+			input:    "*,-email,-author.id",
+			expected: FieldsNode{Fields: []string{"id", "name", "created_at"}, Required: []string{"id", "name", "created_at"}},
+		},
+		{
+			name:     "exclusion before wildcard",
+			input:    "-createdAt, *",
+			expected: FieldsNode{Fields: []string{"id", "name", "email", "author.id"}, Required: []string{"id", "name", "email", "author.id"}},
+		},
+		{
+			name:     "fields are de-duplicated in order",
+			input:    "email, *, name, email",
+			expected: FieldsNode{Fields: []string{"email", "id", "name", "author.id", "created_at"}, Required: []string{"email", "id", "name", "author.id", "created_at"}},
+		},
+		{
+			name:  "preset",
+			input: "@summary, email",
+			expected: FieldsNode{
+				Fields:   []string{"id", "name", "created_at", "email"},
+				Required: []string{"id", "name", "created_at", "email"},
+			},
+		},
+		{
+			name:     "preset with fields hidden from the caller",
+			input:    "@audit",
+			expected: FieldsNode{Fields: []string{"id"}, Required: []string{"id"}},
+		},
+		{
+			name:     "preset with role and exclusion",
+			input:    "@audit,-id",
+			roles:    []string{"admin"},
+			expected: FieldsNode{Fields: []string{"password_hash"}, Required: []string{"password_hash"}},
+		},
+		{
+			name:     "exclude object",
+			input:    "*,-author",
+			expected: FieldsNode{Fields: []string{"id", "name", "email", "created_at"}, Required: []string{"id", "name", "email", "created_at"}},
+		},
+		{
+			name:  "wildcard and nested selection",
+			input: "*, author(id)",
+			expected: FieldsNode{
+				Fields:   []string{"id", "name", "email", "created_at", "author"},
+				Nested:   map[string]FieldsNode{"author": {Fields: []string{"id"}}},
+				Required: []string{"id", "name", "email", "created_at", "author.id"},
+			},
+		},
+		{
+			name:        "preset with all fields hidden from the caller",
+			input:       "@secrets",
+			expectedErr: &QFVFieldsError{Message: "no fields selected"},
+		},
+		{
+			name:        "exclude unknown field",
+			input:       "*,-password",
+			expectedErr: &QFVFieldsError{Field: "password", Message: "cannot exclude unknown field", Pos: scanner.Position{Line: 1, Column: 4}},
+		},
+		{
+			name:        "exclude field hidden from the caller",
+			input:       "*,-password_hash",
+			expectedErr: &QFVFieldsError{Field: "password_hash", Message: "cannot exclude unknown field", Pos: scanner.Position{Line: 1, Column: 4}},
+		},
+		{
+			name:        "unknown preset",
+			input:       "@detail",
+			expectedErr: &QFVFieldsError{Field: "detail", Message: "unknown field preset", Pos: scanner.Position{Line: 1, Column: 2}},
+		},
+		{
+			name:        "missing excluded field",
+			input:       "*, - email",
+			expectedErr: &QFVFieldsError{Field: "-", Message: "missing field after exclusion", Pos: scanner.Position{Line: 1, Column: 4}},
+		},
+		{
+			name:        "missing preset name",
+			input:       "@",
+			expectedErr: &QFVFieldsError{Field: "@", Message: "missing preset name", Pos: scanner.Position{Line: 1, Column: 1}},
+		},
+		{
+			name:        "all fields excluded",
+			input:       "name,-name",
+			expectedErr: &QFVFieldsError{Message: "all selected fields are excluded"},
+		},
+		{
+			name:        "wildcard in nested selection",
+			input:       "author(*)",
+			expectedErr: &QFVFieldsError{Field: "*", Message: "wildcards, exclusions and presets are only allowed at the top level", Pos: scanner.Position{Line: 1, Column: 8}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := parser.ParseWithRoles(tt.input, tt.roles...)
+			if tt.expectedErr != nil {
+				if err == nil || err.Error() != tt.expectedErr.Error() {
+					t.Fatalf("expected error '%v', got '%v'", tt.expectedErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("expected '%v', got '%v'", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFieldsParser_InvalidPreset(t *testing.T) {
+	parser := NewFieldsParser([]string{"id", "name"}, WithFieldPreset("summary", "id", "idd"))
+
+	want := "preset summary: unknown field idd"
+	if _, err := parser.Parse("id"); err == nil || err.Error() != want {
+		t.Errorf("expected error '%s', got '%v'", want, err)
+	}
+}
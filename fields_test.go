@@ -0,0 +1,378 @@
+package qfv
+
+import (
+	"reflect"
+	"testing"
+	"text/scanner"
+)
+
+func newRoleRegistry(t *testing.T) *FieldRegistry {
+	t.Helper()
+
+	registry, err := NewFieldRegistry(
+		FieldDef{Name: "name"},
+		FieldDef{Name: "email", Permissions: []string{"admin", "support"}},
+		FieldDef{Name: "internal_notes", Permissions: []string{"admin"}},
+	)
+	if err != nil {
+		t.Fatalf("NewFieldRegistry() error = %v", err)
+	}
+
+	return registry
+}
+
+func TestNewFieldRegistry(t *testing.T) {
+	tests := []struct {
+		name    string
+		defs    []FieldDef
+		wantErr string
+	}{
+		{
+			name: "valid fields",
+			defs: []FieldDef{{Name: "name"}, {Name: "email", Permissions: []string{"admin"}}},
+		},
+		{
+			name:    "missing name",
+			defs:    []FieldDef{{Permissions: []string{"admin"}}},
+			wantErr: "field name is required",
+		},
+		{
+			name:    "duplicate field",
+			defs:    []FieldDef{{Name: "name"}, {Name: "name"}},
+			wantErr: "field name is already registered",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewFieldRegistry(tt.defs...)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("NewFieldRegistry() error = %v, want nil", err)
+				}
+				return
+			}
+
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("NewFieldRegistry() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFieldRegistry_Fields(t *testing.T) {
+	registry := newRoleRegistry(t)
+
+	tests := []struct {
+		name  string
+		roles []string
+		want  []string
+	}{
+		{name: "no roles", want: []string{"name"}},
+		{name: "support", roles: []string{"support"}, want: []string{"email", "name"}},
+		{name: "admin", roles: []string{"user", "admin"}, want: []string{"email", "internal_notes", "name"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := registry.Fields(tt.roles...); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Fields() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWithRoles(t *testing.T) {
+	registry := newRoleRegistry(t)
+	filterParser := NewFilterParser([]string{"age"}, WithFieldRegistry(registry))
+	sortParser := NewSortParser([]string{"age"}, WithFieldRegistry(registry))
+	fieldsParser := NewFieldsParser([]string{"age"}, WithFieldRegistry(registry))
+
+	tests := []struct {
+		name      string
+		roles     []string
+		filter    string
+		sort      string
+		fields    string
+		wantField string // Field rejected by every parser, empty when all succeed
+	}{
+		{
+			name:   "public fields without roles",
+			filter: "name = 'John' AND age > 3",
+			sort:   "name ASC, age DESC",
+			fields: "name, age",
+		},
+		{
+			name:      "restricted field without roles",
+			filter:    "email = 'a@b.c'",
+			sort:      "email ASC",
+			fields:    "email",
+			wantField: "email",
+		},
+		{
+			name:   "restricted field with role",
+			roles:  []string{"support"},
+			filter: "email = 'a@b.c'",
+			sort:   "email ASC",
+			fields: "email",
+		},
+		{
+			name:      "field restricted to another role",
+			roles:     []string{"support"},
+			filter:    "internal_notes LIKE '%vip%'",
+			sort:      "internal_notes DESC",
+			fields:    "internal_notes",
+			wantField: "internal_notes",
+		},
+		{
+			name:   "admin",
+			roles:  []string{"admin"},
+			filter: "internal_notes LIKE '%vip%' OR email = 'a@b.c'",
+			sort:   "internal_notes DESC, email ASC",
+			fields: "internal_notes, email, name, age",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, filterErr := filterParser.ParseWithRoles(tt.filter, tt.roles...)
+			_, sortErr := sortParser.ParseWithRoles(tt.sort, tt.roles...)
+			_, fieldsErr := fieldsParser.ParseWithRoles(tt.fields, tt.roles...)
+
+			if tt.wantField == "" {
+				for _, err := range []error{filterErr, sortErr, fieldsErr} {
+					if err != nil {
+						t.Errorf("ParseWithRoles() error = %v, want nil", err)
+					}
+				}
+				return
+			}
+
+			wantErrs := []error{
+				&QFVFilterError{Message: "parsing errors: [" + (&QFVFilterError{Field: tt.wantField, Message: "field not allowed"}).Error() + "]"},
+				&QFVSortError{Field: tt.wantField, Message: "field not allowed for sorting", Pos: scanner.Position{Offset: 0, Line: 1, Column: 1}},
+				&QFVFieldsError{Field: tt.wantField, Message: "unknown field", Pos: scanner.Position{Offset: 0, Line: 1, Column: 1}},
+			}
+			for i, err := range []error{filterErr, sortErr, fieldsErr} {
+				if !reflect.DeepEqual(err, wantErrs[i]) {
+					t.Errorf("ParseWithRoles() error = %v, want %v", err, wantErrs[i])
+				}
+			}
+		})
+	}
+
+	// Parse uses no roles
+	if _, err := filterParser.Parse("email = 'a@b.c'"); err == nil {
+		t.Errorf("Parse() error = nil, want field not allowed")
+	}
+}
+
+func TestFieldRegistry_AliasConflicts(t *testing.T) {
+	tests := []struct {
+		name    string
+		defs    []FieldDef
+		wantErr string
+	}{
+		{
+			name:    "alias of itself",
+			defs:    []FieldDef{{Name: "created_at", Aliases: []string{"created_at"}}},
+			wantErr: "field created_at: alias created_at is already registered",
+		},
+		{
+			name:    "alias repeated as deprecated alias",
+			defs:    []FieldDef{{Name: "created_at", Aliases: []string{"createdAt"}, DeprecatedAliases: []string{"createdAt"}}},
+			wantErr: "field created_at: alias createdAt is already registered",
+		},
+		{
+			name:    "alias of another field",
+			defs:    []FieldDef{{Name: "created_at", Aliases: []string{"date"}}, {Name: "updated_at", Aliases: []string{"date"}}},
+			wantErr: "field updated_at: alias date is already registered",
+		},
+		{
+			name:    "alias is another field",
+			defs:    []FieldDef{{Name: "created_at"}, {Name: "updated_at", Aliases: []string{"created_at"}}},
+			wantErr: "field updated_at: alias created_at is already registered",
+		},
+		{
+			name:    "alias with different wildcards",
+			defs:    []FieldDef{{Name: "labels.*", Aliases: []string{"tags"}}},
+			wantErr: "field labels.*: alias tags must have the same wildcards as the field",
+		},
+		{
+			name:    "field is another alias",
+			defs:    []FieldDef{{Name: "created_at", Aliases: []string{"date"}}, {Name: "date"}},
+			wantErr: "field date is already registered",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewFieldRegistry(tt.defs...)
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("NewFieldRegistry() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFieldAliases(t *testing.T) {
+	registry, err := NewFieldRegistry(
+		FieldDef{Name: "created_at", Aliases: []string{"createdAt"}, DeprecatedAliases: []string{"creation_date"}},
+		FieldDef{Name: "tenant_id", Aliases: []string{"tenant"}, Permissions: []string{"admin"}},
+	)
+	if err != nil {
+		t.Fatalf("NewFieldRegistry() error = %v", err)
+	}
+
+	var deprecated []string
+	handler := WithDeprecatedAliasHandler(func(alias, field string) {
+		deprecated = append(deprecated, alias+"->"+field)
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		deprecated = nil
+		parser := NewFilterParser([]string{"name"}, WithFieldRegistry(registry), handler)
+
+		node, err := parser.Parse("createdAt > '2024-01-01' AND creation_date < '2025-01-01' AND name = created_at")
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+
+		want := "(((created_at > '2024-01-01') AND (created_at < '2025-01-01')) AND (name = created_at))"
+		if node.String() != want {
+			t.Errorf("Parse() = %s, want %s", node, want)
+		}
+
+		var originals []string
+		Inspect(node, func(n Node) bool {
+			if id, ok := n.(*IdentifierNode); ok {
+				originals = append(originals, id.Original())
+			}
+			return true
+		})
+		if want := []string{"createdAt", "creation_date", "name", "created_at"}; !reflect.DeepEqual(originals, want) {
+			t.Errorf("Original() = %v, want %v", originals, want)
+		}
+
+		if want := []string{"creation_date->created_at"}; !reflect.DeepEqual(deprecated, want) {
+			t.Errorf("deprecated aliases = %v, want %v", deprecated, want)
+		}
+	})
+
+	t.Run("filter alias keeps permissions", func(t *testing.T) {
+		parser := NewFilterParser(nil, WithFieldRegistry(registry))
+
+		_, err := parser.Parse("tenant = 1")
+		want := "error on field 'tenant': field not allowed"
+		if err == nil || err.Error() != "error: parsing errors: ["+want+"]" {
+			t.Errorf("Parse() error = %v, want %q", err, want)
+		}
+
+		node, err := parser.ParseWithRoles("tenant = 1", "admin")
+		if err != nil {
+			t.Fatalf("ParseWithRoles() error = %v", err)
+		}
+
+		policy := FilterPolicy{Required: []RequiredPredicate{{Field: "tenant_id"}}}
+		if err := policy.Check(node); err != nil {
+			t.Errorf("Check() error = %v, want nil", err)
+		}
+	})
+
+	t.Run("filter errors use aliases", func(t *testing.T) {
+		parser := NewFilterParser(nil,
+			WithFieldRegistry(registry),
+			WithFieldOperators("created_at", TokenOperatorEqual, TokenOperatorRegexMatchCS),
+		)
+		if err := parser.RegisterFunction(FunctionLower); err != nil {
+			t.Fatalf("RegisterFunction() error = %v", err)
+		}
+
+		tests := []struct {
+			input string
+			want  string
+		}{
+			{"createdAt > '2024-01-01'", "error at 1:11: operator > is not permitted on field createdAt"},
+			{"lower(createdAt) = 1", "error on field 'lower(createdAt)': cannot compare STRING with NUMBER"},
+			{"createdAt ~ '('", "error on field 'createdAt' at 1:13: invalid pattern: error parsing regexp: missing closing ): `(`"},
+			{"createdAt bogus", "error on field 'createdAt': unexpected token after field"},
+		}
+
+		for _, tt := range tests {
+			_, err := parser.Parse(tt.input)
+			if err == nil || err.Error() != "error: parsing errors: ["+tt.want+"]" {
+				t.Errorf("Parse(%q) error = %v, want %q", tt.input, err, tt.want)
+			}
+		}
+
+		node, err := parser.ParseWithRoles("tenant = 1", "admin")
+		if err != nil {
+			t.Fatalf("ParseWithRoles() error = %v", err)
+		}
+
+		policy := FilterPolicy{Forbidden: []string{"tenant_id"}}
+		want := "error on field 'tenant' at 1:1: field is forbidden by policy"
+		if err := policy.Check(node); err == nil || err.Error() != want {
+			t.Errorf("Check() error = %v, want %q", err, want)
+		}
+	})
+
+	t.Run("sort", func(t *testing.T) {
+		deprecated = nil
+		parser := NewSortParser(nil, WithFieldRegistry(registry), handler)
+
+		got, err := parser.ParseWithRoles("createdAt DESC, tenant ASC", "admin")
+		if err != nil {
+			t.Fatalf("ParseWithRoles() error = %v", err)
+		}
+
+		want := SortNode{Fields: []SortFieldNode{
+			{Field: "created_at", Alias: "createdAt", Direction: SortDesc},
+			{Field: "tenant_id", Alias: "tenant", Direction: SortAsc},
+		}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ParseWithRoles() = %v, want %v", got, want)
+		}
+
+		deprecatedSort, err := parser.Parse("creation_date ASC")
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+
+		wantDeprecated := SortFieldNode{Field: "created_at", Alias: "creation_date", Direction: SortAsc}
+		if !reflect.DeepEqual(deprecatedSort.Fields[0], wantDeprecated) {
+			t.Errorf("Parse() = %v, want %v", deprecatedSort.Fields[0], wantDeprecated)
+		}
+
+		_, err = parser.ParseWithRoles("createdAt ASC, tenant ASC, createdAt DESC", "admin")
+		if want := "error on field 'createdAt' at 1:28: duplicate sort field"; err == nil || err.Error() != want {
+			t.Errorf("ParseWithRoles() error = %v, want %q", err, want)
+		}
+
+		if got.Fields[0].Original() != "createdAt" {
+			t.Errorf("Original() = %s, want createdAt", got.Fields[0].Original())
+		}
+
+		if want := []string{"creation_date->created_at"}; !reflect.DeepEqual(deprecated, want) {
+			t.Errorf("deprecated aliases = %v, want %v", deprecated, want)
+		}
+	})
+
+	t.Run("fields", func(t *testing.T) {
+		parser := NewFieldsParser([]string{"name"}, WithFieldRegistry(registry))
+
+		got, err := parser.Parse("name, createdAt")
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+
+		want := FieldsNode{
+			Fields:   []string{"name", "created_at"},
+			Aliases:  map[string]string{"created_at": "createdAt"},
+			Required: []string{"name", "created_at"},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Parse() = %v, want %v", got, want)
+		}
+	})
+}
@@ -0,0 +1,306 @@
+package qfv
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Dialect represents the SQL dialect used to render expressions
+type Dialect string
+
+const (
+	DialectPostgres Dialect = "postgres"
+	DialectMySQL    Dialect = "mysql"
+	DialectSQLite   Dialect = "sqlite"
+)
+
+func (d Dialect) String() string {
+	return string(d)
+}
+
+// ValueType represents the type of a value in an expression
+type ValueType string
+
+const (
+	ValueTypeAny       ValueType = "ANY"       // Any represents a value of unknown type (e.g. a field)
+	ValueTypeString    ValueType = "STRING"    // String represents a string value
+	ValueTypeNumber    ValueType = "NUMBER"    // Number represents an integer or floating-point value
+	ValueTypeBoolean   ValueType = "BOOLEAN"   // Boolean represents a boolean value
+	ValueTypeTimestamp ValueType = "TIMESTAMP" // Timestamp represents a date/time value, written as a string literal
+)
+
+func (vt ValueType) String() string {
+	return string(vt)
+}
+
+// accepts reports whether a value of type other can be used where vt is expected
+func (vt ValueType) accepts(other ValueType) bool {
+	switch {
+	case vt == ValueTypeAny || other == ValueTypeAny:
+		return true
+	case vt == other:
+		return true
+	case vt == ValueTypeTimestamp && other == ValueTypeString,
+		vt == ValueTypeString && other == ValueTypeTimestamp:
+		// Timestamps are written as string literals
+		return true
+	default:
+		return false
+	}
+}
+
+// valueTypeOf returns the type of the value produced by the node
+func valueTypeOf(node Node) ValueType {
+	switch n := node.(type) {
+	case *LiteralNode:
+		switch n.Kind {
+		case reflect.String:
+			return ValueTypeString
+		case reflect.Int, reflect.Int64, reflect.Uint64, reflect.Float32, reflect.Float64:
+			return ValueTypeNumber
+		case reflect.Bool:
+			return ValueTypeBoolean
+		}
+	case *FunctionCallNode:
+		return n.Function.Returns
+	}
+
+	return ValueTypeAny
+}
+
+// Function describes a scalar function that may be called in expressions
+type Function struct {
+	Name    string      // Name of the function, matched case-insensitively
+	Args    []ValueType // Types of the arguments, in order
+	Returns ValueType   // Type of the returned value
+
+	// Templates holds the fmt template used to render the call for each dialect.
+	// Rendered arguments are referenced as %[1]s, %[2]s, ...
+	Templates map[Dialect]string
+
+	// Validate optionally performs extra checks on the parsed arguments
+	Validate func(args []Node) error
+
+	// Eval optionally evaluates the function in memory on non-null arguments,
+	// so SortNode.Compare orders rows like the database
+	Eval func(args []any) (any, error)
+}
+
+// Render renders a call to the function for the given dialect,
+// using the already rendered arguments
+func (f Function) Render(d Dialect, args ...string) (string, error) {
+	if len(args) != len(f.Args) {
+		return "", fmt.Errorf("function %s expects %d arguments, got %d", f.Name, len(f.Args), len(args))
+	}
+
+	tmpl, ok := f.Templates[d]
+	if !ok {
+		return "", fmt.Errorf("function %s is not supported by dialect %s", f.Name, d)
+	}
+
+	values := make([]any, len(args))
+	for i, a := range args {
+		values[i] = a
+	}
+
+	return fmt.Sprintf(tmpl, values...), nil
+}
+
+// check returns the problems with the arguments of a call to the function
+func (f Function) check(args []Node) []string {
+	if len(args) != len(f.Args) {
+		return []string{fmt.Sprintf("expected %d arguments, got %d", len(f.Args), len(args))}
+	}
+
+	var problems []string
+	for i, arg := range args {
+		if argType := valueTypeOf(arg); !f.Args[i].accepts(argType) {
+			problems = append(problems, fmt.Sprintf("argument %d must be %s, got %s", i+1, f.Args[i], argType))
+		}
+	}
+
+	if f.Validate != nil {
+		if err := f.Validate(args); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	return problems
+}
+
+// Built-in functions that can be registered on the parsers
+var (
+	FunctionLower = Function{
+		Name:    "lower",
+		Args:    []ValueType{ValueTypeString},
+		Returns: ValueTypeString,
+		Templates: map[Dialect]string{
+			DialectPostgres: "LOWER(%[1]s)",
+			DialectMySQL:    "LOWER(%[1]s)",
+			DialectSQLite:   "LOWER(%[1]s)",
+		},
+		Eval: evalString(strings.ToLower),
+	}
+
+	FunctionUpper = Function{
+		Name:    "upper",
+		Args:    []ValueType{ValueTypeString},
+		Returns: ValueTypeString,
+		Templates: map[Dialect]string{
+			DialectPostgres: "UPPER(%[1]s)",
+			DialectMySQL:    "UPPER(%[1]s)",
+			DialectSQLite:   "UPPER(%[1]s)",
+		},
+		Eval: evalString(strings.ToUpper),
+	}
+
+	FunctionLength = Function{
+		Name:    "length",
+		Args:    []ValueType{ValueTypeString},
+		Returns: ValueTypeNumber,
+		Templates: map[Dialect]string{
+			DialectPostgres: "CHAR_LENGTH(%[1]s)",
+			DialectMySQL:    "CHAR_LENGTH(%[1]s)", // LENGTH counts bytes in MySQL
+			DialectSQLite:   "LENGTH(%[1]s)",
+		},
+		Eval: evalLength,
+	}
+
+	FunctionDateTrunc = Function{
+		Name:    "date_trunc",
+		Args:    []ValueType{ValueTypeString, ValueTypeTimestamp},
+		Returns: ValueTypeTimestamp,
+		Templates: map[Dialect]string{
+			DialectPostgres: "DATE_TRUNC(%[1]s, %[2]s)",
+		},
+		Validate: validateDateTruncUnit,
+		Eval:     evalDateTrunc,
+	}
+)
+
+// dateTruncUnits are the units accepted by date_trunc
+var dateTruncUnits = map[string]any{
+	"microseconds": struct{}{},
+	"milliseconds": struct{}{},
+	"second":       struct{}{},
+	"minute":       struct{}{},
+	"hour":         struct{}{},
+	"day":          struct{}{},
+	"week":         struct{}{},
+	"month":        struct{}{},
+	"quarter":      struct{}{},
+	"year":         struct{}{},
+	"decade":       struct{}{},
+	"century":      struct{}{},
+	"millennium":   struct{}{},
+}
+
+// validateDateTruncUnit checks that the first argument of date_trunc is a known unit literal
+func validateDateTruncUnit(args []Node) error {
+	unit, ok := args[0].(*LiteralNode)
+	if !ok || unit.Kind != reflect.String {
+		return fmt.Errorf("expected string literal unit, got %s", args[0].Type())
+	}
+
+	if _, ok := dateTruncUnits[strings.ToLower(unit.Value.(string))]; !ok {
+		return fmt.Errorf("unknown unit %s", unit.Text)
+	}
+
+	return nil
+}
+
+// evalString evaluates a string function of one argument with f
+func evalString(f func(string) string) func(args []any) (any, error) {
+	return func(args []any) (any, error) {
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string, got %T", args[0])
+		}
+
+		return f(s), nil
+	}
+}
+
+// evalLength returns the number of characters of the string argument
+func evalLength(args []any) (any, error) {
+	s, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("expected string, got %T", args[0])
+	}
+
+	return int64(utf8.RuneCountInString(s)), nil
+}
+
+// evalDateTrunc truncates the time argument to the unit, like date_trunc in Postgres
+func evalDateTrunc(args []any) (any, error) {
+	unit, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("expected string unit, got %T", args[0])
+	}
+
+	t, ok := args[1].(time.Time)
+	if !ok {
+		return nil, fmt.Errorf("expected time, got %T", args[1])
+	}
+
+	year, month, day := t.Date()
+	hour, minute, second := t.Clock()
+	date := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, t.Location()) }
+
+	switch strings.ToLower(unit) {
+	case "microseconds":
+		return t.Truncate(time.Microsecond), nil
+	case "milliseconds":
+		return t.Truncate(time.Millisecond), nil
+	case "second":
+		return time.Date(year, month, day, hour, minute, second, 0, t.Location()), nil
+	case "minute":
+		return time.Date(year, month, day, hour, minute, 0, 0, t.Location()), nil
+	case "hour":
+		return time.Date(year, month, day, hour, 0, 0, 0, t.Location()), nil
+	case "day":
+		return date(year, month, day), nil
+	case "week":
+		return date(year, month, day-(int(t.Weekday())+6)%7), nil // Weeks start on Monday
+	case "month":
+		return date(year, month, 1), nil
+	case "quarter":
+		return date(year, month-(month-1)%3, 1), nil
+	case "year":
+		return date(year, time.January, 1), nil
+	case "decade":
+		return date(year-year%10, time.January, 1), nil
+	case "century":
+		return date((year-1)/100*100+1, time.January, 1), nil
+	case "millennium":
+		return date((year-1)/1000*1000+1, time.January, 1), nil
+	default:
+		return nil, fmt.Errorf("unknown unit %s", unit)
+	}
+}
+
+// functionRegistry holds the functions allowed in expressions, keyed by lowercase name
+type functionRegistry map[string]Function
+
+// register adds the function to the registry
+func (r functionRegistry) register(fn Function) error {
+	if fn.Name == "" {
+		return fmt.Errorf("function name is required")
+	}
+
+	name := strings.ToLower(fn.Name)
+	if _, exists := r[name]; exists {
+		return fmt.Errorf("function %s is already registered", name)
+	}
+
+	if fn.Returns == "" {
+		fn.Returns = ValueTypeAny
+	}
+
+	fn.Name = name
+	r[name] = fn
+	return nil
+}
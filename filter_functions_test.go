@@ -0,0 +1,90 @@
+package qfv
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFunction_Render(t *testing.T) {
+	tests := []struct {
+		name    string
+		fn      Function
+		dialect Dialect
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{"lower postgres", FunctionLower, DialectPostgres, []string{"email"}, "LOWER(email)", false},
+		{"length postgres", FunctionLength, DialectPostgres, []string{"name"}, "CHAR_LENGTH(name)", false},
+		{"length mysql", FunctionLength, DialectMySQL, []string{"name"}, "CHAR_LENGTH(name)", false},
+		{"length sqlite", FunctionLength, DialectSQLite, []string{"name"}, "LENGTH(name)", false},
+		{"date_trunc postgres", FunctionDateTrunc, DialectPostgres, []string{"'day'", "created_at"}, "DATE_TRUNC('day', created_at)", false},
+		{"date_trunc mysql unsupported", FunctionDateTrunc, DialectMySQL, []string{"'day'", "created_at"}, "", true},
+		{"wrong number of arguments", FunctionUpper, DialectPostgres, []string{"a", "b"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.fn.Render(tt.dialect, tt.args...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Render() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("Render() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFunction_Eval(t *testing.T) {
+	ts := time.Date(2024, time.May, 16, 13, 45, 30, 123456789, time.UTC) // A Thursday
+
+	tests := []struct {
+		name    string
+		fn      Function
+		args    []any
+		want    any
+		wantErr bool
+	}{
+		{"lower", FunctionLower, []any{"JoHn"}, "john", false},
+		{"upper", FunctionUpper, []any{"JoHn"}, "JOHN", false},
+		{"length counts characters", FunctionLength, []any{"héllo"}, int64(5), false},
+		{"lower of a number", FunctionLower, []any{42}, nil, true},
+		{"date_trunc second", FunctionDateTrunc, []any{"second", ts}, time.Date(2024, time.May, 16, 13, 45, 30, 0, time.UTC), false},
+		{"date_trunc hour", FunctionDateTrunc, []any{"HOUR", ts}, time.Date(2024, time.May, 16, 13, 0, 0, 0, time.UTC), false},
+		{"date_trunc week", FunctionDateTrunc, []any{"week", ts}, time.Date(2024, time.May, 13, 0, 0, 0, 0, time.UTC), false},
+		{"date_trunc quarter", FunctionDateTrunc, []any{"quarter", ts}, time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC), false},
+		{"date_trunc century", FunctionDateTrunc, []any{"century", ts}, time.Date(2001, time.January, 1, 0, 0, 0, 0, time.UTC), false},
+		{"date_trunc unknown unit", FunctionDateTrunc, []any{"fortnight", ts}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.fn.Eval(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Eval() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("Eval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterParser_RegisterFunction(t *testing.T) {
+	p := NewFilterParser([]string{"name"})
+
+	if err := p.RegisterFunction(Function{}); err == nil {
+		t.Errorf("expected error for function without name")
+	}
+
+	if err := p.RegisterFunction(FunctionLower); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := p.RegisterFunction(Function{Name: "LOWER"}); err == nil {
+		t.Errorf("expected error for duplicated function")
+	}
+}
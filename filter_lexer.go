@@ -12,6 +12,9 @@ type Lexer struct {
 	inputLen int
 	pos      int
 	tokens   []Token
+
+	// operators maps the custom operator symbols and keywords to their token types
+	operators map[string]TokenType
 }
 
 // NewLexer creates a new lexer
@@ -19,7 +22,8 @@ func NewLexer(input string) *Lexer {
 	var s scanner.Scanner
 	s.Init(strings.NewReader(input))
 	// Customize scanner: recognize identifiers, numbers, strings.
-	s.Mode = scanner.ScanIdents | scanner.ScanFloats | scanner.ScanStrings
+	s.Mode = scanner.ScanIdents | scanner.ScanFloats
+	s.IsIdentRune = isIdentRune                         // Identifiers may be dotted paths (e.g. address.city)
 	s.Whitespace = 1<<'\t' | 1<<'\n' | 1<<'\r' | 1<<' ' // Define whitespace chars
 	s.Error = func(*scanner.Scanner, string) {}         // Suppress default errors
 
@@ -37,42 +41,60 @@ func (l *Lexer) Parse() {
 		lit := l.s.TokenText()
 		var tok TokenType
 
+		// Custom operator symbols take precedence over the built-in ones they extend
+		if scanTok > 0 && l.isSymbolPrefix(string(scanTok)) {
+			if symbolTok, symbolLit, ok := l.scanSymbol(scanTok); ok {
+				l.tokens = append(l.tokens, Token{Pos: pos, Type: symbolTok, Value: symbolLit})
+				continue
+			}
+		}
+
 		switch scanTok {
 		case scanner.EOF:
 			tok = TokenEOF
 		case scanner.Ident:
-			// Special handling for the "is" token in the test cases
-			// In the test cases, "is" is expected to be an identifier, not a keyword
-			if lit == "is" {
-				tok = TokenIdentifier
-			} else {
-				upperLit := strings.ToUpper(lit)
-				switch upperLit {
-				case "AND":
-					tok = TokenOperatorAnd
-				case "OR":
-					tok = TokenOperatorOr
-				case "LIKE":
-					tok = TokenOperatorLike
-				case "IN":
-					tok = TokenOperatorIn
-				case "BETWEEN":
-					tok = TokenOperatorBetween
-				case "DISTINCT":
-					tok = TokenOperatorDistinct
-				case "SIMILAR":
-					tok = TokenOperatorSimilarTo // Treat SIMILAR as its own token
-				case "TO":
-					tok = TokenIdentifier // Treat TO as a generic identifier for now, parser will handle context
-				case "IS":
-					tok = TokenOperatorIsNull // Treat IS as its own token
-				case "NOT":
-					tok = TokenOperatorNot // Treat NOT as its own token
-				case "TRUE", "FALSE", "YES", "NO":
-					tok = TokenBoolean
-				case "NULL":
-					tok = TokenIdentifier // Treat NULL as an identifier
-				default:
+			upperLit := strings.ToUpper(lit)
+			switch upperLit {
+			case "AND":
+				tok = TokenOperatorAnd
+			case "OR":
+				tok = TokenOperatorOr
+			case "LIKE":
+				tok = TokenOperatorLike
+			case "ILIKE":
+				tok = TokenOperatorILike
+			case "CONTAINS":
+				tok = TokenOperatorContains
+			case "STARTS":
+				tok = TokenOperatorStartsWith // Parser expects WITH next
+			case "ENDS":
+				tok = TokenOperatorEndsWith // Parser expects WITH next
+			case "WITH":
+				tok = TokenIdentifier // Treat WITH as a generic identifier, like TO
+			case "ESCAPE":
+				tok = TokenIdentifier // Treat ESCAPE as a generic identifier, like TO
+			case "IN":
+				tok = TokenOperatorIn
+			case "BETWEEN":
+				tok = TokenOperatorBetween
+			case "DISTINCT":
+				tok = TokenOperatorDistinct
+			case "SIMILAR":
+				tok = TokenOperatorSimilarTo // Treat SIMILAR as its own token
+			case "TO":
+				tok = TokenIdentifier // Treat TO as a generic identifier for now, parser will handle context
+			case "IS":
+				tok = TokenOperatorIsNull // Treat IS as its own token
+			case "NOT":
+				tok = TokenOperatorNot // Treat NOT as its own token
+			case "TRUE", "FALSE", "YES", "NO":
+				tok = TokenBoolean
+			case "NULL":
+				tok = TokenIdentifier // Treat NULL as an identifier
+			default:
+				if opTok, ok := l.operators[upperLit]; ok {
+					tok = opTok // Custom operator keyword
+				} else {
 					// Check if it's a potential field name or other identifier
 					tok = TokenIdentifier
 				}
@@ -81,10 +103,14 @@ func (l *Lexer) Parse() {
 			tok = TokenInt
 		case scanner.Float:
 			tok = TokenFloat
-		case scanner.String: // Built-in scanner string (double quotes) - treat as illegal for this SQL-like syntax
-			tok = TokenIllegal
-			// For the test cases, we need to ensure the token value matches the expected format
-			// The test expects double quotes for these tokens
+		case '"', '`': // Quoted identifier, a field name that may contain any character or be a keyword
+			if name, ok := scanQuoted(&l.s, scanTok); ok && name != "" {
+				tok = TokenQuotedIdentifier
+				lit = name
+			} else {
+				tok = TokenIllegal
+				lit = string(scanTok) + name // Show partial content for debugging
+			}
 		case '\'': // Start of a single-quoted string literal
 			var sb strings.Builder
 			isValid := true
@@ -132,7 +158,6 @@ func (l *Lexer) Parse() {
 			tok = TokenComma
 		case '=':
 			tok = TokenOperatorEqual
-		case '+':
 		case '<':
 			if l.s.Peek() == '=' {
 				l.s.Scan()
@@ -180,19 +205,6 @@ func (l *Lexer) Parse() {
 				tok = TokenOperatorRegexMatchCS
 				lit = "~"
 			}
-		case '"':
-			// Special handling for the double quote character
-			// In the test case bad_double_quoted_string_missing_opening_quote, the test expects '"'' as the token value
-			tok = TokenIllegal
-
-			// Check if the next character is a single quote
-			if l.s.Peek() == '\'' {
-				l.s.Next() // Consume the single quote
-				// Hardcode the exact string expected by the test
-				lit = "\"'\"" // This is exactly what the test expects
-			} else {
-				lit = "\"" // Just the double quote
-			}
 		default:
 			// Handle other single characters if necessary
 			tok = TokenIllegal
@@ -207,6 +219,62 @@ func (l *Lexer) Parse() {
 	}
 }
 
+// scanQuoted scans the rest of a quoted identifier after the opening quote, returning
+// its unquoted content. A doubled quote stands for the quote character itself
+// (e.g. "say ""hi"""). It returns false when the closing quote is missing.
+func scanQuoted(s *scanner.Scanner, quote rune) (string, bool) {
+	var sb strings.Builder
+	for {
+		switch ch := s.Next(); ch {
+		case scanner.EOF:
+			return sb.String(), false
+		case quote:
+			if s.Peek() != quote {
+				return sb.String(), true
+			}
+			sb.WriteRune(s.Next())
+		default:
+			sb.WriteRune(ch)
+		}
+	}
+}
+
+// isSymbolPrefix reports whether lit is the beginning of a custom operator symbol
+func (l *Lexer) isSymbolPrefix(lit string) bool {
+	for symbol := range l.operators {
+		if strings.HasPrefix(symbol, lit) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// scanSymbol scans the longest custom operator symbol starting with ch.
+// It returns false when nothing beyond ch was consumed and ch alone is not a
+// custom symbol, so the caller can handle ch as a built-in token.
+func (l *Lexer) scanSymbol(ch rune) (TokenType, string, bool) {
+	lit := string(ch)
+	for next := l.s.Peek(); next != scanner.EOF && l.isSymbolPrefix(lit+string(next)); next = l.s.Peek() {
+		lit += string(l.s.Next())
+	}
+
+	if tok, ok := l.operators[lit]; ok {
+		return tok, lit, true
+	}
+
+	if len(lit) == 1 {
+		return "", "", false
+	}
+
+	// Consumed a partial custom symbol, which may still be a built-in one (e.g. <= while <=> is registered)
+	if tok, ok := builtinSymbols[lit]; ok {
+		return tok, lit, true
+	}
+
+	return TokenIllegal, lit, true
+}
+
 // Peek returns the next token without consuming it.
 func (l *Lexer) Peek() Token {
 	if l.pos+1 >= len(l.tokens) {
@@ -12,25 +12,25 @@ func TestLexer_Navigation(t *testing.T) {
 		expected []Token
 	}{
 		{
-			name:  "double quoted string, 1",
+			name:  "double quoted identifier, 1",
 			input: `"comment = 'This is a string'"`,
 			expected: []Token{
-				{Pos: scanner.Position{Line: 1, Column: 1}, Type: TokenIllegal, Value: `"comment = 'This is a string'"`},
+				{Pos: scanner.Position{Line: 1, Column: 1}, Type: TokenQuotedIdentifier, Value: `comment = 'This is a string'`},
 				{Pos: scanner.Position{Line: 1, Column: 34}, Type: TokenEOF, Value: ""},
 			},
 		},
 		{
-			name:  "double quoted string, 2",
+			name:  "double quoted identifier, 2",
 			input: `comment = "'This is a string'"`,
 			expected: []Token{
 				{Pos: scanner.Position{Line: 1, Column: 1}, Type: TokenIdentifier, Value: "comment"},
 				{Pos: scanner.Position{Line: 1, Column: 9}, Type: TokenOperatorEqual, Value: "="},
-				{Pos: scanner.Position{Line: 1, Column: 1}, Type: TokenIllegal, Value: `"'This is a string'"`},
+				{Pos: scanner.Position{Line: 1, Column: 11}, Type: TokenQuotedIdentifier, Value: `'This is a string'`},
 				{Pos: scanner.Position{Line: 1, Column: 34}, Type: TokenEOF, Value: ""},
 			},
 		},
 		{
-			name:  "bad double quoted string, missing closing quote",
+			name:  "bad double quoted identifier, missing closing quote",
 			input: `comment = "'This is a bad string`,
 			expected: []Token{
 				{Pos: scanner.Position{Line: 1, Column: 1}, Type: TokenIdentifier, Value: "comment"},
@@ -56,7 +56,7 @@ func TestLexer_Navigation(t *testing.T) {
 				{Pos: scanner.Position{Line: 1, Column: 1}, Type: TokenIdentifier, Value: "comment"},
 				{Pos: scanner.Position{Line: 1, Column: 9}, Type: TokenOperatorEqual, Value: "="},
 				{Pos: scanner.Position{Line: 1, Column: 11}, Type: TokenIdentifier, Value: "This"},
-				{Pos: scanner.Position{Line: 1, Column: 16}, Type: TokenIdentifier, Value: "is"},
+				{Pos: scanner.Position{Line: 1, Column: 16}, Type: TokenOperatorIsNull, Value: "is"},
 				{Pos: scanner.Position{Line: 1, Column: 19}, Type: TokenIdentifier, Value: "a"},
 				{Pos: scanner.Position{Line: 1, Column: 21}, Type: TokenIdentifier, Value: "bad"},
 				{Pos: scanner.Position{Line: 1, Column: 25}, Type: TokenIdentifier, Value: "string"},
@@ -71,7 +71,7 @@ func TestLexer_Navigation(t *testing.T) {
 				{Pos: scanner.Position{Line: 1, Column: 1}, Type: TokenIdentifier, Value: "comment"},
 				{Pos: scanner.Position{Line: 1, Column: 9}, Type: TokenOperatorEqual, Value: "="},
 				{Pos: scanner.Position{Line: 1, Column: 11}, Type: TokenIdentifier, Value: "This"},
-				{Pos: scanner.Position{Line: 1, Column: 16}, Type: TokenIdentifier, Value: "is"},
+				{Pos: scanner.Position{Line: 1, Column: 16}, Type: TokenOperatorIsNull, Value: "is"},
 				{Pos: scanner.Position{Line: 1, Column: 19}, Type: TokenIdentifier, Value: "a"},
 				{Pos: scanner.Position{Line: 1, Column: 21}, Type: TokenIdentifier, Value: "bad"},
 				{Pos: scanner.Position{Line: 1, Column: 25}, Type: TokenIdentifier, Value: "string"},
@@ -239,6 +239,73 @@ func TestLexer_Navigation(t *testing.T) {
 				{Pos: scanner.Position{Line: 1, Column: 30}, Type: TokenEOF, Value: ""},
 			},
 		},
+		{
+			name:  "STARTS WITH",
+			input: "name STARTS WITH 'Jo'",
+			expected: []Token{
+				{Pos: scanner.Position{Line: 1, Column: 1}, Type: TokenIdentifier, Value: "name"},
+				{Pos: scanner.Position{Line: 1, Column: 6}, Type: TokenOperatorStartsWith, Value: "STARTS"}, // STARTS token
+				{Pos: scanner.Position{Line: 1, Column: 13}, Type: TokenIdentifier, Value: "WITH"},          // WITH token
+				{Pos: scanner.Position{Line: 1, Column: 18}, Type: TokenString, Value: "'Jo'"},
+				{Pos: scanner.Position{Line: 1, Column: 22}, Type: TokenEOF, Value: ""},
+			},
+		},
+		{
+			name:  "quoted identifiers",
+			input: "\"first-name\" = 'John' AND `order` = 1 OR \"say \"\"hi\"\"\" IS NULL",
+			expected: []Token{
+				{Pos: scanner.Position{Line: 1, Column: 1}, Type: TokenQuotedIdentifier, Value: "first-name"},
+				{Pos: scanner.Position{Line: 1, Column: 14}, Type: TokenOperatorEqual, Value: "="},
+				{Pos: scanner.Position{Line: 1, Column: 16}, Type: TokenString, Value: "'John'"},
+				{Pos: scanner.Position{Line: 1, Column: 23}, Type: TokenOperatorAnd, Value: "AND"},
+				{Pos: scanner.Position{Line: 1, Column: 27}, Type: TokenQuotedIdentifier, Value: "order"},
+				{Pos: scanner.Position{Line: 1, Column: 35}, Type: TokenOperatorEqual, Value: "="},
+				{Pos: scanner.Position{Line: 1, Column: 37}, Type: TokenInt, Value: "1"},
+				{Pos: scanner.Position{Line: 1, Column: 39}, Type: TokenOperatorOr, Value: "OR"},
+				{Pos: scanner.Position{Line: 1, Column: 42}, Type: TokenQuotedIdentifier, Value: `say "hi"`},
+				{Pos: scanner.Position{Line: 1, Column: 55}, Type: TokenOperatorIsNull, Value: "IS"},
+				{Pos: scanner.Position{Line: 1, Column: 58}, Type: TokenIdentifier, Value: "NULL"},
+				{Pos: scanner.Position{Line: 1, Column: 62}, Type: TokenEOF, Value: ""},
+			},
+		},
+		{
+			name:  "empty quoted identifier",
+			input: "`` = 1",
+			expected: []Token{
+				{Pos: scanner.Position{Line: 1, Column: 1}, Type: TokenIllegal, Value: "`"},
+				{Pos: scanner.Position{Line: 1, Column: 4}, Type: TokenOperatorEqual, Value: "="},
+				{Pos: scanner.Position{Line: 1, Column: 6}, Type: TokenInt, Value: "1"},
+				{Pos: scanner.Position{Line: 1, Column: 7}, Type: TokenEOF, Value: ""},
+			},
+		},
+		{
+			name:  "dotted path",
+			input: "address.city = 'Berlin' AND labels.env2 = 1.5",
+			expected: []Token{
+				{Pos: scanner.Position{Line: 1, Column: 1}, Type: TokenIdentifier, Value: "address.city"},
+				{Pos: scanner.Position{Line: 1, Column: 14}, Type: TokenOperatorEqual, Value: "="},
+				{Pos: scanner.Position{Line: 1, Column: 16}, Type: TokenString, Value: "'Berlin'"},
+				{Pos: scanner.Position{Line: 1, Column: 25}, Type: TokenOperatorAnd, Value: "AND"},
+				{Pos: scanner.Position{Line: 1, Column: 29}, Type: TokenIdentifier, Value: "labels.env2"},
+				{Pos: scanner.Position{Line: 1, Column: 41}, Type: TokenOperatorEqual, Value: "="},
+				{Pos: scanner.Position{Line: 1, Column: 43}, Type: TokenFloat, Value: "1.5"},
+				{Pos: scanner.Position{Line: 1, Column: 46}, Type: TokenEOF, Value: ""},
+			},
+		},
+		{
+			name:  "CONTAINS and ILIKE",
+			input: "name contains 'oh' OR name ilike 'j%'",
+			expected: []Token{
+				{Pos: scanner.Position{Line: 1, Column: 1}, Type: TokenIdentifier, Value: "name"},
+				{Pos: scanner.Position{Line: 1, Column: 6}, Type: TokenOperatorContains, Value: "contains"},
+				{Pos: scanner.Position{Line: 1, Column: 15}, Type: TokenString, Value: "'oh'"},
+				{Pos: scanner.Position{Line: 1, Column: 20}, Type: TokenOperatorOr, Value: "OR"},
+				{Pos: scanner.Position{Line: 1, Column: 23}, Type: TokenIdentifier, Value: "name"},
+				{Pos: scanner.Position{Line: 1, Column: 28}, Type: TokenOperatorILike, Value: "ilike"},
+				{Pos: scanner.Position{Line: 1, Column: 34}, Type: TokenString, Value: "'j%'"},
+				{Pos: scanner.Position{Line: 1, Column: 38}, Type: TokenEOF, Value: ""},
+			},
+		},
 		{
 			name:  "SIMILAR keyword only",
 			input: "name SIMILAR",
@@ -0,0 +1,122 @@
+package qfv
+
+import (
+	"fmt"
+	"text/scanner"
+)
+
+// FilterLimit identifies one of the limits of FilterLimits
+type FilterLimit string
+
+const (
+	LimitInputLength FilterLimit = "input length"
+	LimitDepth       FilterLimit = "nesting depth"
+	LimitPredicates  FilterLimit = "number of predicates"
+	LimitInListSize  FilterLimit = "list size"
+	LimitRegexCount  FilterLimit = "number of pattern predicates"
+)
+
+func (l FilterLimit) String() string {
+	return string(l)
+}
+
+// DefaultMaxDepth is the nesting depth allowed when FilterLimits.MaxDepth is zero,
+// the parser is recursive and never accepts unbounded nesting
+const DefaultMaxDepth = 64
+
+// FilterLimits bounds the size of the filter expressions accepted by a FilterParser,
+// protecting the database and the parser itself from expensive expressions.
+// A zero value disables the corresponding limit, except MaxDepth which defaults
+// to DefaultMaxDepth.
+type FilterLimits struct {
+	MaxInputLength int // Maximum length of the input, in bytes
+	MaxDepth       int // Maximum nesting of parentheses, NOT and function calls
+	MaxPredicates  int // Maximum number of predicates (e.g. name = 'John')
+	MaxInListSize  int // Maximum number of values in an IN list or a custom operator list
+	MaxRegexCount  int // Maximum number of regex and SIMILAR TO predicates
+}
+
+// WithLimits enforces the limits while parsing
+func WithLimits(limits FilterLimits) FilterOption {
+	return filterOption(func(p *FilterParser) {
+		p.limits = limits
+	})
+}
+
+// QFVLimitError is returned when a filter expression exceeds one of the FilterLimits
+type QFVLimitError struct {
+	Limit FilterLimit
+	Max   int
+	Pos   scanner.Position // Position where the limit was exceeded, if known
+}
+
+func (e *QFVLimitError) Error() string {
+	if e.Pos.IsValid() {
+		return fmt.Sprintf("error at %d:%d: filter exceeds maximum %s of %d", e.Pos.Line, e.Pos.Column, e.Limit, e.Max)
+	}
+
+	return fmt.Sprintf("error: filter exceeds maximum %s of %d", e.Limit, e.Max)
+}
+
+// exceed records the exceeded limit and stops parsing by skipping to the end of the input
+func (p *FilterParser) exceed(limit FilterLimit, max int, pos scanner.Position) {
+	if p.limitErr == nil {
+		p.limitErr = &QFVLimitError{Limit: limit, Max: max, Pos: pos}
+	}
+
+	p.lexer.pos = len(p.lexer.tokens) - 1
+	p.currentToken = p.lexer.Current()
+}
+
+// enter increases the nesting depth, returning false when it exceeds the limit
+func (p *FilterParser) enter() bool {
+	maxDepth := p.limits.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxDepth
+	}
+
+	p.depth++
+	if p.depth > maxDepth {
+		p.exceed(LimitDepth, maxDepth, p.currentToken.Pos)
+		return false
+	}
+
+	return true
+}
+
+// leave decreases the nesting depth
+func (p *FilterParser) leave() {
+	p.depth--
+}
+
+// countPredicate counts a new predicate, returning false when it exceeds the limit
+func (p *FilterParser) countPredicate(pos scanner.Position) bool {
+	p.predicates++
+	if p.limits.MaxPredicates > 0 && p.predicates > p.limits.MaxPredicates {
+		p.exceed(LimitPredicates, p.limits.MaxPredicates, pos)
+		return false
+	}
+
+	return true
+}
+
+// countRegex counts a new regex or SIMILAR TO predicate, returning false when it exceeds the limit
+func (p *FilterParser) countRegex(pos scanner.Position) bool {
+	p.regexCount++
+	if p.limits.MaxRegexCount > 0 && p.regexCount > p.limits.MaxRegexCount {
+		p.exceed(LimitRegexCount, p.limits.MaxRegexCount, pos)
+		return false
+	}
+
+	return true
+}
+
+// checkListSize checks the number of values of a list, returning false when it exceeds the limit
+func (p *FilterParser) checkListSize(size int, pos scanner.Position) bool {
+	if p.limits.MaxInListSize > 0 && size > p.limits.MaxInListSize {
+		p.exceed(LimitInListSize, p.limits.MaxInListSize, pos)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,139 @@
+package qfv
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFilterParser_Limits(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		limits    FilterLimits
+		wantLimit FilterLimit
+	}{
+		{
+			name:   "no limits",
+			input:  strings.Repeat("(", DefaultMaxDepth) + "name = 'John'" + strings.Repeat(")", DefaultMaxDepth),
+			limits: FilterLimits{},
+		},
+		{
+			name:      "default depth",
+			input:     strings.Repeat("(", 100000) + "name = 'John'" + strings.Repeat(")", 100000),
+			limits:    FilterLimits{},
+			wantLimit: LimitDepth,
+		},
+		{
+			name:      "input too long",
+			input:     "name = '" + strings.Repeat("a", 100) + "'",
+			limits:    FilterLimits{MaxInputLength: 64},
+			wantLimit: LimitInputLength,
+		},
+		{
+			name:   "input within length",
+			input:  "name = 'John'",
+			limits: FilterLimits{MaxInputLength: 64},
+		},
+		{
+			name:      "parentheses too deep",
+			input:     strings.Repeat("(", 100000) + "name = 'John'" + strings.Repeat(")", 100000),
+			limits:    FilterLimits{MaxDepth: 32},
+			wantLimit: LimitDepth,
+		},
+		{
+			name:      "NOT too deep",
+			input:     strings.Repeat("NOT ", 100) + "name = 'John'",
+			limits:    FilterLimits{MaxDepth: 32},
+			wantLimit: LimitDepth,
+		},
+		{
+			name:      "function calls too deep",
+			input:     strings.Repeat("lower(", 10) + "name" + strings.Repeat(")", 10) + " = 'john'",
+			limits:    FilterLimits{MaxDepth: 5},
+			wantLimit: LimitDepth,
+		},
+		{
+			name:   "nesting within depth",
+			input:  "NOT ((name = 'John') OR (age > 3))",
+			limits: FilterLimits{MaxDepth: 3},
+		},
+		{
+			name:      "too many predicates",
+			input:     strings.TrimSuffix(strings.Repeat("name = 'John' OR ", 1000), " OR "),
+			limits:    FilterLimits{MaxPredicates: 100},
+			wantLimit: LimitPredicates,
+		},
+		{
+			name:      "too many literal predicates",
+			input:     strings.TrimSuffix(strings.Repeat("true OR ", 1000), " OR "),
+			limits:    FilterLimits{MaxPredicates: 100},
+			wantLimit: LimitPredicates,
+		},
+		{
+			name:   "predicates within limit",
+			input:  "name = 'John' OR name = 'Jane' AND age > 3",
+			limits: FilterLimits{MaxPredicates: 3},
+		},
+		{
+			name:      "IN list too large",
+			input:     "age IN (" + strings.TrimSuffix(strings.Repeat("1, ", 1000), ", ") + ")",
+			limits:    FilterLimits{MaxInListSize: 100},
+			wantLimit: LimitInListSize,
+		},
+		{
+			name:   "IN list within limit",
+			input:  "age IN (1, 2, 3)",
+			limits: FilterLimits{MaxInListSize: 3},
+		},
+		{
+			name:      "too many regex predicates",
+			input:     "name ~ 'a' OR name ~* 'b' OR name SIMILAR TO 'c%'",
+			limits:    FilterLimits{MaxRegexCount: 2},
+			wantLimit: LimitRegexCount,
+		},
+		{
+			name:   "regex predicates within limit",
+			input:  "name ~ 'a' OR name NOT SIMILAR TO 'c%'",
+			limits: FilterLimits{MaxRegexCount: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewFilterParser([]string{"name", "age"}, WithLimits(tt.limits))
+			if err := p.RegisterFunction(FunctionLower); err != nil {
+				t.Fatalf("RegisterFunction() error = %v", err)
+			}
+
+			_, err := p.Parse(tt.input)
+
+			if tt.wantLimit == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var limitErr *QFVLimitError
+			if !errors.As(err, &limitErr) {
+				t.Fatalf("expected QFVLimitError, got %v", err)
+			}
+			if limitErr.Limit != tt.wantLimit {
+				t.Errorf("expected limit %s, got %s", tt.wantLimit, limitErr.Limit)
+			}
+		})
+	}
+}
+
+func TestQFVLimitError_Error(t *testing.T) {
+	err := &QFVLimitError{Limit: LimitDepth, Max: 32}
+	if got, want := err.Error(), "error: filter exceeds maximum nesting depth of 32"; got != want {
+		t.Errorf("Error() = %v, want %v", got, want)
+	}
+
+	err.Pos.Line, err.Pos.Column = 1, 33
+	if got, want := err.Error(), "error at 1:33: filter exceeds maximum nesting depth of 32"; got != want {
+		t.Errorf("Error() = %v, want %v", got, want)
+	}
+}
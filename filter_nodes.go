@@ -28,6 +28,12 @@ const (
 	NodeTypeSimilarTo      NodeType = "SIMILAR_TO"      // (field, pattern) -> name SIMILAR TO "pattern"
 	NodeTypeNotSimilarTo   NodeType = "NOT_SIMILAR_TO"  // (field, pattern) -> name NOT SIMILAR TO "pattern"
 	NodeTypeRegexMatch     NodeType = "REGEX_MATCH"     // (field, pattern, is_not, is_case_insensitive) -> name ~ 'pattern'
+	NodeTypeLike           NodeType = "LIKE"            // (field, pattern, escape) -> name LIKE 'J%' ESCAPE '\'
+	NodeTypeContains       NodeType = "CONTAINS"        // (field, value) -> name CONTAINS 'oh'
+	NodeTypeStartsWith     NodeType = "STARTS_WITH"     // (field, value) -> name STARTS WITH 'Jo'
+	NodeTypeEndsWith       NodeType = "ENDS_WITH"       // (field, value) -> name ENDS WITH 'hn'
+	NodeTypeFunctionCall   NodeType = "FUNCTION_CALL"   // (name, args) -> lower(email)
+	NodeTypeList           NodeType = "LIST"            // (values) -> ('red', 'blue')
 
 	NodeTypeSort      NodeType = "SORT"       // (field, direction) -> name ASC, age DESC
 	NodeTypeSortField NodeType = "SORT_FIELD" // (field, direction) -> name ASC, age DESC
@@ -70,6 +76,140 @@ func (n *RegexMatchNode) String() string {
 }
 func (n *RegexMatchNode) Pos() scanner.Position { return n.pos }
 
+// LikeNode represents a LIKE or ILIKE expression (e.g., name LIKE 'J%' ESCAPE '!')
+type LikeNode struct {
+	baseNode
+	Field             Node
+	Pattern           Node
+	Escape            string // Escape character of the ESCAPE clause, empty if there is none
+	IsNot             bool   // true for NOT LIKE
+	IsCaseInsensitive bool   // true for ILIKE
+}
+
+func (n *LikeNode) Type() NodeType { return NodeTypeLike }
+func (n *LikeNode) String() string {
+	op := "LIKE"
+	if n.IsCaseInsensitive {
+		op = "ILIKE"
+	}
+
+	if n.Escape != "" {
+		return fmt.Sprintf("%s %s %s ESCAPE '%s'", n.Field.String(), op, n.Pattern.String(), n.Escape)
+	}
+
+	return fmt.Sprintf("%s %s %s", n.Field.String(), op, n.Pattern.String())
+}
+func (n *LikeNode) Pos() scanner.Position { return n.pos }
+
+// StringMatchNode represents a literal substring match (e.g., name CONTAINS '50%').
+// Unlike LIKE, wildcard characters in the value have no special meaning.
+type StringMatchNode struct {
+	baseNode
+	Field    Node
+	Value    Node
+	Operator TokenType // CONTAINS, STARTS WITH or ENDS WITH
+	IsNot    bool      // true for NOT CONTAINS, NOT STARTS WITH and NOT ENDS WITH
+}
+
+func (n *StringMatchNode) Type() NodeType {
+	switch n.Operator {
+	case TokenOperatorStartsWith:
+		return NodeTypeStartsWith
+	case TokenOperatorEndsWith:
+		return NodeTypeEndsWith
+	default:
+		return NodeTypeContains
+	}
+}
+func (n *StringMatchNode) String() string {
+	return fmt.Sprintf("%s %s %s", n.Field.String(), n.Operator, n.Value.String())
+}
+func (n *StringMatchNode) Pos() scanner.Position { return n.pos }
+
+// LikePattern returns the equivalent LIKE pattern, with the wildcards in the value
+// escaped using escape (e.g. name LIKE '%50\%%' ESCAPE '\' for name CONTAINS '50%')
+func (n *StringMatchNode) LikePattern(escape rune) string {
+	var value string
+	if lit, ok := n.Value.(*LiteralNode); ok {
+		value, _ = lit.Value.(string)
+	}
+
+	value = EscapeLike(value, escape)
+	switch n.Operator {
+	case TokenOperatorStartsWith:
+		return value + "%"
+	case TokenOperatorEndsWith:
+		return "%" + value
+	default:
+		return "%" + value + "%"
+	}
+}
+
+// EscapeLike escapes the LIKE wildcards (% and _) and the escape character itself,
+// so the value is matched literally by LIKE ... ESCAPE
+func EscapeLike(value string, escape rune) string {
+	var sb strings.Builder
+	for _, r := range value {
+		if r == '%' || r == '_' || r == escape {
+			sb.WriteRune(escape)
+		}
+		sb.WriteRune(r)
+	}
+
+	return sb.String()
+}
+
+// FunctionCallNode represents a call to a registered function (e.g., lower(email))
+type FunctionCallNode struct {
+	baseNode
+	Name     string
+	Args     []Node
+	Function Function // Definition of the called function
+}
+
+func (n *FunctionCallNode) Type() NodeType { return NodeTypeFunctionCall }
+func (n *FunctionCallNode) String() string {
+	var args []string
+	for _, a := range n.Args {
+		args = append(args, a.String())
+	}
+
+	return fmt.Sprintf("%s(%s)", n.Name, strings.Join(args, ", "))
+}
+func (n *FunctionCallNode) Pos() scanner.Position { return n.pos }
+
+// ListNode represents a parenthesized list of values (e.g., ('red', 'blue'))
+type ListNode struct {
+	baseNode
+	Values []Node
+}
+
+func (n *ListNode) Type() NodeType { return NodeTypeList }
+func (n *ListNode) String() string {
+	var values []string
+	for _, v := range n.Values {
+		values = append(values, v.String())
+	}
+
+	return fmt.Sprintf("(%s)", strings.Join(values, ", "))
+}
+func (n *ListNode) Pos() scanner.Position { return n.pos }
+
+// CustomOperatorNode represents an expression using an operator registered
+// with FilterParser.RegisterOperator (e.g., tags @> 'admin')
+type CustomOperatorNode struct {
+	baseNode
+	Left     Node
+	Right    Node
+	Operator Operator
+}
+
+func (n *CustomOperatorNode) Type() NodeType { return n.Operator.NodeType }
+func (n *CustomOperatorNode) String() string {
+	return fmt.Sprintf("(%s %s %s)", n.Left.String(), n.Operator.Symbol, n.Right.String())
+}
+func (n *CustomOperatorNode) Pos() scanner.Position { return n.pos }
+
 // String returns the string representation of the NodeType
 func (nt NodeType) String() string {
 	return string(nt)
@@ -102,7 +242,7 @@ type LiteralNode struct {
 func (n *LiteralNode) Type() NodeType { return NodeTypeLiteral }
 func (n *LiteralNode) String() string {
 	switch n.Kind {
-	case reflect.String, reflect.Int, reflect.Int64, reflect.Float32, reflect.Float64:
+	case reflect.String, reflect.Int, reflect.Int64, reflect.Uint64, reflect.Float32, reflect.Float64:
 		return n.Text
 	case reflect.Bool:
 		if n.Value.(bool) {
@@ -118,13 +258,24 @@ func (n *LiteralNode) Pos() scanner.Position { return n.pos }
 // IdentifierNode represents a field name
 type IdentifierNode struct {
 	baseNode
-	Name string
+	Name  string    // Canonical name of the field
+	Alias string    // Name used in the input when it differs from Name (e.g. an alias), empty otherwise
+	Path  FieldPath // Segments of the canonical name when it is a dotted path, nil otherwise
 }
 
 func (n *IdentifierNode) Type() NodeType        { return NodeTypeIdentifier }
 func (n *IdentifierNode) String() string        { return n.Name }
 func (n *IdentifierNode) Pos() scanner.Position { return n.pos }
 
+// Original returns the name of the field as written in the input
+func (n *IdentifierNode) Original() string {
+	if n.Alias != "" {
+		return n.Alias
+	}
+
+	return n.Name
+}
+
 // UnaryOperatorNode (e.g., NOT name, IS NULL name)
 type UnaryOperatorNode struct {
 	baseNode
@@ -215,3 +366,51 @@ func (n *BetweenNode) String() string {
 	return fmt.Sprintf("%s BETWEEN %s AND %s", n.Field.String(), n.Lower.String(), n.Upper.String())
 }
 func (n *BetweenNode) Pos() scanner.Position { return n.pos }
+
+// Inspect traverses the AST in depth-first order, calling f for each node.
+// If f returns false, the children of the node are not visited.
+func Inspect(node Node, f func(Node) bool) {
+	if node == nil || !f(node) {
+		return
+	}
+
+	for _, child := range children(node) {
+		Inspect(child, f)
+	}
+}
+
+// children returns the direct children of the node
+func children(node Node) []Node {
+	switch n := node.(type) {
+	case *BinaryOperatorNode:
+		return []Node{n.Left, n.Right}
+	case *CustomOperatorNode:
+		return []Node{n.Left, n.Right}
+	case *UnaryOperatorNode:
+		return []Node{n.X}
+	case *GroupNode:
+		return []Node{n.Expression}
+	case *IsNullNode:
+		return []Node{n.Field}
+	case *DistinctNode:
+		return []Node{n.Field}
+	case *InNode:
+		return append([]Node{n.Field}, n.Values...)
+	case *BetweenNode:
+		return []Node{n.Field, n.Lower, n.Upper}
+	case *LikeNode:
+		return []Node{n.Field, n.Pattern}
+	case *StringMatchNode:
+		return []Node{n.Field, n.Value}
+	case *SimilarToNode:
+		return []Node{n.Field, n.Pattern}
+	case *RegexMatchNode:
+		return []Node{n.Field, n.Pattern}
+	case *FunctionCallNode:
+		return n.Args
+	case *ListNode:
+		return n.Values
+	default:
+		return nil
+	}
+}
@@ -26,6 +26,11 @@ func TestNodeType_String(t *testing.T) {
 		{"NotBetween", NodeTypeNotBetween, "NOT_BETWEEN"},
 		{"In", NodeTypeIn, "IN"},
 		{"NotIn", NodeTypeNotIn, "NOT_IN"},
+		{"FunctionCall", NodeTypeFunctionCall, "FUNCTION_CALL"},
+		{"Like", NodeTypeLike, "LIKE"},
+		{"Contains", NodeTypeContains, "CONTAINS"},
+		{"StartsWith", NodeTypeStartsWith, "STARTS_WITH"},
+		{"EndsWith", NodeTypeEndsWith, "ENDS_WITH"},
 		{"Sort", NodeTypeSort, "SORT"},
 		{"SortField", NodeTypeSortField, "SORT_FIELD"},
 		{"FieldList", NodeTypeFieldList, "FIELD_LIST"},
@@ -375,3 +380,150 @@ func TestBetweenNode(t *testing.T) {
 		}
 	})
 }
+
+func TestStringMatchNode(t *testing.T) {
+	pos := scanner.Position{Filename: "test.go", Line: 1, Column: 1}
+	fieldNode := &IdentifierNode{
+		baseNode: baseNode{pos: pos},
+		Name:     "fieldName",
+	}
+
+	value := &LiteralNode{
+		baseNode: baseNode{pos: pos},
+		Value:    `50%_off\`,
+		Kind:     reflect.String,
+		Text:     `'50%_off\'`,
+	}
+
+	tests := []struct {
+		operator    TokenType
+		wantType    NodeType
+		wantString  string
+		wantPattern string
+	}{
+		{TokenOperatorContains, NodeTypeContains, `fieldName CONTAINS '50%_off\'`, `%50\%\_off\\%`},
+		{TokenOperatorStartsWith, NodeTypeStartsWith, `fieldName STARTS WITH '50%_off\'`, `50\%\_off\\%`},
+		{TokenOperatorEndsWith, NodeTypeEndsWith, `fieldName ENDS WITH '50%_off\'`, `%50\%\_off\\`},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.operator), func(t *testing.T) {
+			node := &StringMatchNode{
+				baseNode: baseNode{pos: pos},
+				Field:    fieldNode,
+				Value:    value,
+				Operator: tt.operator,
+			}
+
+			if got := node.Type(); got != tt.wantType {
+				t.Errorf("StringMatchNode.Type() = %v, want %v", got, tt.wantType)
+			}
+
+			if got := node.String(); got != tt.wantString {
+				t.Errorf("StringMatchNode.String() = %v, want %v", got, tt.wantString)
+			}
+
+			if got := node.LikePattern('\\'); got != tt.wantPattern {
+				t.Errorf("StringMatchNode.LikePattern() = %v, want %v", got, tt.wantPattern)
+			}
+
+			if got := node.Pos(); got != pos {
+				t.Errorf("StringMatchNode.Pos() = %v, want %v", got, pos)
+			}
+		})
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	tests := []struct {
+		value  string
+		escape rune
+		want   string
+	}{
+		{"plain", '\\', "plain"},
+		{"100%", '\\', `100\%`},
+		{"a_b", '!', "a!_b"},
+		{"wow!", '!', "wow!!"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := EscapeLike(tt.value, tt.escape); got != tt.want {
+				t.Errorf("EscapeLike() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLikeNode(t *testing.T) {
+	pos := scanner.Position{Filename: "test.go", Line: 1, Column: 1}
+	fieldNode := &IdentifierNode{
+		baseNode: baseNode{pos: pos},
+		Name:     "fieldName",
+	}
+
+	pattern := &LiteralNode{
+		baseNode: baseNode{pos: pos},
+		Value:    "a!%%",
+		Kind:     reflect.String,
+		Text:     "'a!%%'",
+	}
+
+	tests := []struct {
+		name string
+		node *LikeNode
+		want string
+	}{
+		{"LIKE", &LikeNode{baseNode: baseNode{pos: pos}, Field: fieldNode, Pattern: pattern}, "fieldName LIKE 'a!%%'"},
+		{"ILIKE", &LikeNode{baseNode: baseNode{pos: pos}, Field: fieldNode, Pattern: pattern, IsCaseInsensitive: true}, "fieldName ILIKE 'a!%%'"},
+		{"ESCAPE", &LikeNode{baseNode: baseNode{pos: pos}, Field: fieldNode, Pattern: pattern, Escape: "!"}, "fieldName LIKE 'a!%%' ESCAPE '!'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.node.Type(); got != NodeTypeLike {
+				t.Errorf("LikeNode.Type() = %v, want %v", got, NodeTypeLike)
+			}
+
+			if got := tt.node.String(); got != tt.want {
+				t.Errorf("LikeNode.String() = %v, want %v", got, tt.want)
+			}
+
+			if got := tt.node.Pos(); got != pos {
+				t.Errorf("LikeNode.Pos() = %v, want %v", got, pos)
+			}
+		})
+	}
+}
+
+func TestInspect(t *testing.T) {
+	parser := NewFilterParser([]string{"name", "age", "email"})
+	node, err := parser.Parse("(name = 'John' OR age BETWEEN 1 AND 3) AND NOT email IN ('a', name)")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	var names []string
+	Inspect(node, func(n Node) bool {
+		if id, ok := n.(*IdentifierNode); ok {
+			names = append(names, id.Name)
+		}
+		return true
+	})
+
+	want := []string{"name", "age", "email", "name"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("Inspect() identifiers = %v, want %v", names, want)
+	}
+
+	var count int
+	Inspect(node, func(n Node) bool {
+		count++
+		return n.Type() != NodeTypeGroup
+	})
+
+	// AND, GROUP, NOT, IN, email, 'a', name
+	if count != 7 {
+		t.Errorf("Inspect() visited %d nodes when pruning groups, want 7", count)
+	}
+}
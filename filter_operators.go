@@ -0,0 +1,327 @@
+package qfv
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"text/scanner"
+	"unicode"
+)
+
+// OperatorPrecedence represents the level at which a custom operator binds
+type OperatorPrecedence int
+
+const (
+	PrecedenceOr         OperatorPrecedence = iota + 1 // Binds like OR, between two expressions
+	PrecedenceAnd                                      // Binds like AND, between two expressions
+	PrecedenceComparison                               // Binds like =, between a field and a value
+)
+
+// Operator describes a custom binary operator that extends the filter grammar
+type Operator struct {
+	Symbol     string             // Symbol (e.g. "@>", "&&") or keyword (e.g. "NEAR"), keywords are case-insensitive
+	Precedence OperatorPrecedence // Level at which the operator binds
+	NodeType   NodeType           // Type reported by the resulting CustomOperatorNode
+
+	// Operand is the type required for the value side of comparison operators.
+	// An empty Operand accepts any value.
+	Operand ValueType
+
+	// List makes the value side of comparison operators a parenthesized
+	// list of values (e.g. tags && ('red', 'blue'))
+	List bool
+
+	// Validate optionally performs extra checks on the operands
+	Validate func(left, right Node) error
+}
+
+// builtinSymbols are the operator symbols recognized by the lexer
+var builtinSymbols = map[string]TokenType{
+	"=":   TokenOperatorEqual,
+	"<>":  TokenOperatorNotEqual,
+	"!=":  TokenOperatorNotEqualAlias,
+	"<":   TokenOperatorLessThan,
+	"<=":  TokenOperatorLessThanOrEqualTo,
+	">":   TokenOperatorGreaterThan,
+	">=":  TokenOperatorGreaterThanOrEqualTo,
+	"~":   TokenOperatorRegexMatchCS,
+	"!~":  TokenOperatorNotRegexMatchCS,
+	"~*":  TokenOperatorRegexMatchCI,
+	"!~*": TokenOperatorNotRegexMatchCI,
+}
+
+// builtinKeywords are the words with a special meaning in the filter grammar
+var builtinKeywords = map[string]any{
+	"AND":      struct{}{},
+	"OR":       struct{}{},
+	"NOT":      struct{}{},
+	"LIKE":     struct{}{},
+	"ILIKE":    struct{}{},
+	"CONTAINS": struct{}{},
+	"STARTS":   struct{}{},
+	"ENDS":     struct{}{},
+	"WITH":     struct{}{},
+	"ESCAPE":   struct{}{},
+	"IN":       struct{}{},
+	"BETWEEN":  struct{}{},
+	"DISTINCT": struct{}{},
+	"FROM":     struct{}{},
+	"SIMILAR":  struct{}{},
+	"TO":       struct{}{},
+	"IS":       struct{}{},
+	"NULL":     struct{}{},
+	"TRUE":     struct{}{},
+	"FALSE":    struct{}{},
+	"YES":      struct{}{},
+	"NO":       struct{}{},
+}
+
+// builtinTokenTypes are the token types that are not operators, custom
+// operators must not reuse them (e.g. a keyword EOF or COMMA)
+var builtinTokenTypes = map[TokenType]any{
+	TokenIdentifier:       struct{}{},
+	TokenQuotedIdentifier: struct{}{},
+	TokenOperator:         struct{}{},
+	TokenString:           struct{}{},
+	TokenBoolean:          struct{}{},
+	TokenLogicalOperation: struct{}{},
+	TokenSortOperation:    struct{}{},
+	TokenLPAREN:           struct{}{},
+	TokenRPAREN:           struct{}{},
+	TokenIllegal:          struct{}{},
+	TokenEOF:              struct{}{},
+	TokenInt:              struct{}{},
+	TokenFloat:            struct{}{},
+	TokenComma:            struct{}{},
+	TokenWhitespace:       struct{}{},
+}
+
+// operatorRegistry holds the custom operators, keyed by their token type
+type operatorRegistry map[TokenType]Operator
+
+// register adds the operator to the registry
+func (r operatorRegistry) register(op Operator) error {
+	if op.Symbol == "" {
+		return fmt.Errorf("operator symbol is required")
+	}
+
+	if op.NodeType == "" {
+		return fmt.Errorf("operator %s: node type is required", op.Symbol)
+	}
+
+	if op.Precedence < PrecedenceOr || op.Precedence > PrecedenceComparison {
+		return fmt.Errorf("operator %s: invalid precedence %d", op.Symbol, op.Precedence)
+	}
+
+	symbol := strings.ToUpper(op.Symbol)
+	if isKeywordSymbol(symbol) {
+		if _, exists := builtinKeywords[symbol]; exists {
+			return fmt.Errorf("operator %s: conflicts with a built-in keyword", op.Symbol)
+		}
+		if _, exists := builtinTokenTypes[TokenType(symbol)]; exists {
+			return fmt.Errorf("operator %s: conflicts with a built-in token", op.Symbol)
+		}
+	} else {
+		for _, r := range symbol {
+			if !isOperatorRune(r) {
+				return fmt.Errorf("operator %s: invalid character %q", op.Symbol, r)
+			}
+		}
+
+		if _, exists := builtinSymbols[symbol]; exists {
+			return fmt.Errorf("operator %s: conflicts with a built-in operator", op.Symbol)
+		}
+	}
+
+	if _, exists := r[TokenType(symbol)]; exists {
+		return fmt.Errorf("operator %s is already registered", op.Symbol)
+	}
+
+	r[TokenType(symbol)] = op
+	return nil
+}
+
+// lookup returns the operator for the token when it binds at the given precedence
+func (r operatorRegistry) lookup(token Token, precedence OperatorPrecedence) (Operator, bool) {
+	op, ok := r[token.Type]
+	if !ok || op.Precedence != precedence {
+		return Operator{}, false
+	}
+
+	return op, true
+}
+
+// isKeywordSymbol reports whether the symbol is a word (e.g. NEAR) rather than punctuation
+func isKeywordSymbol(symbol string) bool {
+	for i, r := range symbol {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isOperatorRune reports whether the rune may be part of an operator symbol
+func isOperatorRune(r rune) bool {
+	return strings.ContainsRune("!#$%&*+-/:<=>?@^|~", r)
+}
+
+// operatorRules restricts the operators allowed in predicates
+type operatorRules struct {
+	allowed map[TokenType]any            // Operators allowed globally, nil allows all
+	denied  map[TokenType]any            // Operators disabled globally
+	byField map[string]map[TokenType]any // Operators allowed per field
+}
+
+// WithOperators allows only the given predicate operators (e.g. TokenOperatorEqual,
+// TokenOperatorIn), every other operator is rejected. Negated forms such as
+// TokenOperatorNotIn must be listed explicitly.
+func WithOperators(ops ...TokenType) FilterOption {
+	return filterOption(func(p *FilterParser) {
+		if p.operatorRules.allowed == nil {
+			p.operatorRules.allowed = make(map[TokenType]any, len(ops))
+		}
+		for _, op := range ops {
+			p.operatorRules.allowed[op] = struct{}{}
+		}
+	})
+}
+
+// WithoutOperators disables the given predicate operators
+// (e.g. TokenOperatorRegexMatchCS, TokenOperatorSimilarTo)
+func WithoutOperators(ops ...TokenType) FilterOption {
+	return filterOption(func(p *FilterParser) {
+		if p.operatorRules.denied == nil {
+			p.operatorRules.denied = make(map[TokenType]any, len(ops))
+		}
+		for _, op := range ops {
+			p.operatorRules.denied[op] = struct{}{}
+		}
+	})
+}
+
+// WithFieldOperators allows only the given predicate operators on the field,
+// in addition to the global rules (e.g. email supports only = and IN). The field
+// may be an alias, and unknown fields make every parse fail.
+func WithFieldOperators(field string, ops ...TokenType) FilterOption {
+	return filterOption(func(p *FilterParser) {
+		if p.operatorRules.byField == nil {
+			p.operatorRules.byField = make(map[string]map[TokenType]any)
+		}
+		if p.operatorRules.byField[field] == nil {
+			p.operatorRules.byField[field] = make(map[TokenType]any, len(ops))
+		}
+		for _, op := range ops {
+			p.operatorRules.byField[field][op] = struct{}{}
+		}
+	})
+}
+
+// resolve keys the per-field rules by the canonical names of the fields
+func (r *operatorRules) resolve(fields *fieldSet) error {
+	if r.byField == nil {
+		return nil
+	}
+
+	byField := make(map[string]map[TokenType]any, len(r.byField))
+	for _, name := range slices.Sorted(maps.Keys(r.byField)) {
+		canonical, ok := fields.known(name)
+		if !ok {
+			return fmt.Errorf("operators of field %s: unknown field", name)
+		}
+
+		if byField[canonical] == nil {
+			byField[canonical] = make(map[TokenType]any, len(r.byField[name]))
+		}
+		maps.Copy(byField[canonical], r.byField[name])
+	}
+	r.byField = byField
+
+	return nil
+}
+
+// permits returns an error message if the operator is not permitted on the fields
+func (r operatorRules) permits(op TokenType, fields []*IdentifierNode) (string, bool) {
+	if _, denied := r.denied[op]; denied {
+		return fmt.Sprintf("operator %s is not permitted", op), false
+	}
+
+	if r.allowed != nil {
+		if _, ok := r.allowed[op]; !ok {
+			return fmt.Sprintf("operator %s is not permitted", op), false
+		}
+	}
+
+	for _, field := range fields {
+		allowed, ok := r.byField[field.Name]
+		if !ok {
+			continue
+		}
+
+		if _, ok := allowed[op]; !ok {
+			return fmt.Sprintf("operator %s is not permitted on field %s", op, field.Original()), false
+		}
+	}
+
+	return "", true
+}
+
+// negatedOperators maps the operators that may follow NOT to their negated form
+var negatedOperators = map[TokenType]TokenType{
+	TokenOperatorIn:         TokenOperatorNotIn,
+	TokenOperatorBetween:    TokenOperatorNotBetween,
+	TokenOperatorLike:       TokenOperatorNotLike,
+	TokenOperatorILike:      TokenOperatorNotILike,
+	TokenOperatorSimilarTo:  TokenOperatorNotSimilarTo,
+	TokenOperatorDistinct:   TokenOperatorNotDistinct,
+	TokenOperatorContains:   TokenOperatorNotContains,
+	TokenOperatorStartsWith: TokenOperatorNotStartsWith,
+	TokenOperatorEndsWith:   TokenOperatorNotEndsWith,
+	TokenOperatorIsNull:     TokenOperatorIsNotNull,
+}
+
+// predicateOperator returns the operator of the predicate starting at the current
+// token, combining multi-token forms (e.g. NOT IN, IS NOT NULL), without consuming it
+func (p *FilterParser) predicateOperator() TokenType {
+	switch p.currentToken.Type {
+	case TokenOperatorNot:
+		if negated, ok := negatedOperators[p.lexer.Peek().Type]; ok {
+			return negated
+		}
+	case TokenOperatorIsNull:
+		if p.lexer.Peek().Type == TokenOperatorNot {
+			return TokenOperatorIsNotNull
+		}
+	}
+
+	return p.currentToken.Type
+}
+
+// checkOperator checks that the operator op, found at pos, is permitted on every
+// field referenced by the predicate, on either side of the operator
+func (p *FilterParser) checkOperator(op TokenType, pos scanner.Position, predicate Node) {
+	switch op {
+	case TokenEOF, TokenIllegal, TokenIdentifier, TokenQuotedIdentifier, TokenString, TokenInt, TokenFloat, TokenBoolean,
+		TokenComma, TokenLPAREN, TokenRPAREN, TokenOperatorAnd, TokenOperatorOr, TokenOperatorNot:
+		return // Not an operator, reported as a syntax error while parsing the predicate
+	}
+
+	if msg, ok := p.operatorRules.permits(op, referencedFields(predicate)); !ok {
+		p.addError(&QFVFilterError{Message: msg, Pos: pos})
+	}
+}
+
+// referencedFields returns the fields referenced by the expression
+func referencedFields(node Node) []*IdentifierNode {
+	var fields []*IdentifierNode
+	Inspect(node, func(n Node) bool {
+		if id, ok := n.(*IdentifierNode); ok {
+			fields = append(fields, id)
+		}
+		return true
+	})
+
+	return fields
+}
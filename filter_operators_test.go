@@ -0,0 +1,374 @@
+package qfv
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const (
+	nodeTypeContains  NodeType = "CONTAINS"
+	nodeTypeOverlaps  NodeType = "OVERLAPS"
+	nodeTypeNear      NodeType = "NEAR"
+	nodeTypeXor       NodeType = "XOR"
+	nodeTypeSpaceship NodeType = "SPACESHIP"
+)
+
+func newCustomOperatorsParser(t *testing.T) *FilterParser {
+	t.Helper()
+
+	p := NewFilterParser([]string{"tags", "location", "name", "age"})
+	operators := []Operator{
+		{Symbol: "@>", Precedence: PrecedenceComparison, NodeType: nodeTypeContains, Operand: ValueTypeString},
+		{Symbol: "&&", Precedence: PrecedenceComparison, NodeType: nodeTypeOverlaps, List: true},
+		{Symbol: "<=>", Precedence: PrecedenceComparison, NodeType: nodeTypeSpaceship},
+		{
+			Symbol:     "near",
+			Precedence: PrecedenceComparison,
+			NodeType:   nodeTypeNear,
+			Validate: func(left, right Node) error {
+				if _, ok := right.(*LiteralNode); !ok {
+					return fmt.Errorf("expected a literal location")
+				}
+				return nil
+			},
+		},
+		{Symbol: "XOR", Precedence: PrecedenceOr, NodeType: nodeTypeXor},
+	}
+
+	for _, op := range operators {
+		if err := p.RegisterOperator(op); err != nil {
+			t.Fatalf("RegisterOperator(%s) error = %v", op.Symbol, err)
+		}
+	}
+
+	return p
+}
+
+func TestFilterParser_CustomOperators(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantErr   bool
+		checkNode func(t *testing.T, node Node)
+	}{
+		{
+			name:    "symbol operator",
+			input:   "tags @> 'admin'",
+			wantErr: false,
+			checkNode: func(t *testing.T, node Node) {
+				custom, ok := node.(*CustomOperatorNode)
+				if !ok {
+					t.Fatalf("expected CustomOperatorNode, got %T", node)
+				}
+				if custom.Type() != nodeTypeContains {
+					t.Errorf("expected node type %s, got %s", nodeTypeContains, custom.Type())
+				}
+				if custom.String() != "(tags @> 'admin')" {
+					t.Errorf("unexpected string representation: %s", custom.String())
+				}
+			},
+		},
+		{
+			name:    "symbol operator without spaces",
+			input:   "tags@>'admin'",
+			wantErr: false,
+		},
+		{
+			name:    "operand type constraint",
+			input:   "tags @> 42",
+			wantErr: true,
+		},
+		{
+			name:    "list operand",
+			input:   "tags && ('red', 'blue')",
+			wantErr: false,
+			checkNode: func(t *testing.T, node Node) {
+				custom, ok := node.(*CustomOperatorNode)
+				if !ok {
+					t.Fatalf("expected CustomOperatorNode, got %T", node)
+				}
+				list, ok := custom.Right.(*ListNode)
+				if !ok {
+					t.Fatalf("expected ListNode for right operand, got %T", custom.Right)
+				}
+				if len(list.Values) != 2 {
+					t.Errorf("expected 2 values, got %d", len(list.Values))
+				}
+			},
+		},
+		{
+			name:    "list operand without parenthesis",
+			input:   "tags && 'red'",
+			wantErr: true,
+		},
+		{
+			name:    "keyword operator is case-insensitive",
+			input:   "location NEAR 'Berlin' AND name = 'John'",
+			wantErr: false,
+			checkNode: func(t *testing.T, node Node) {
+				binOp, ok := node.(*BinaryOperatorNode)
+				if !ok {
+					t.Fatalf("expected BinaryOperatorNode, got %T", node)
+				}
+				if binOp.Left.Type() != nodeTypeNear {
+					t.Errorf("expected node type %s, got %s", nodeTypeNear, binOp.Left.Type())
+				}
+			},
+		},
+		{
+			name:    "keyword operator validation",
+			input:   "location near name",
+			wantErr: true,
+		},
+		{
+			name:    "custom symbol extending a built-in one",
+			input:   "age <=> 30",
+			wantErr: false,
+			checkNode: func(t *testing.T, node Node) {
+				if node.Type() != nodeTypeSpaceship {
+					t.Errorf("expected node type %s, got %s", nodeTypeSpaceship, node.Type())
+				}
+			},
+		},
+		{
+			name:    "built-in symbol sharing a prefix with a custom one",
+			input:   "age <= 30",
+			wantErr: false,
+			checkNode: func(t *testing.T, node Node) {
+				binOp, ok := node.(*BinaryOperatorNode)
+				if !ok {
+					t.Fatalf("expected BinaryOperatorNode, got %T", node)
+				}
+				if binOp.Operator != TokenOperatorLessThanOrEqualTo {
+					t.Errorf("expected <= operator, got %s", binOp.Operator)
+				}
+			},
+		},
+		{
+			name:    "logical operator precedence",
+			input:   "name = 'a' XOR name = 'b' AND age > 3",
+			wantErr: false,
+			checkNode: func(t *testing.T, node Node) {
+				custom, ok := node.(*CustomOperatorNode)
+				if !ok {
+					t.Fatalf("expected CustomOperatorNode, got %T", node)
+				}
+				if right, ok := custom.Right.(*BinaryOperatorNode); !ok || right.Operator != TokenOperatorAnd {
+					t.Errorf("expected AND to bind tighter than XOR, got %s", custom.Right)
+				}
+			},
+		},
+		{
+			name:    "custom operator field not allowed",
+			input:   "secret @> 'x'",
+			wantErr: true,
+		},
+		{
+			name:    "unregistered symbol",
+			input:   "tags @ 'x'",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newCustomOperatorsParser(t)
+			node, err := p.Parse(tt.input)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if err == nil && tt.checkNode != nil {
+				tt.checkNode(t, node)
+			}
+		})
+	}
+}
+
+func TestFilterParser_RegisterOperator(t *testing.T) {
+	tests := []struct {
+		name    string
+		op      Operator
+		wantErr bool
+	}{
+		{"valid symbol", Operator{Symbol: "@>", Precedence: PrecedenceComparison, NodeType: nodeTypeContains}, false},
+		{"valid keyword", Operator{Symbol: "NEAR", Precedence: PrecedenceComparison, NodeType: nodeTypeNear}, false},
+		{"missing symbol", Operator{Precedence: PrecedenceComparison, NodeType: nodeTypeNear}, true},
+		{"missing node type", Operator{Symbol: "@>", Precedence: PrecedenceComparison}, true},
+		{"missing precedence", Operator{Symbol: "@>", NodeType: nodeTypeContains}, true},
+		{"built-in keyword", Operator{Symbol: "like", Precedence: PrecedenceComparison, NodeType: nodeTypeNear}, true},
+		{"built-in token", Operator{Symbol: "eof", Precedence: PrecedenceOr, NodeType: nodeTypeXor}, true},
+		{"built-in token comma", Operator{Symbol: "Comma", Precedence: PrecedenceOr, NodeType: nodeTypeXor}, true},
+		{"built-in symbol", Operator{Symbol: "<=", Precedence: PrecedenceComparison, NodeType: nodeTypeNear}, true},
+		{"invalid characters", Operator{Symbol: "@ >", Precedence: PrecedenceComparison, NodeType: nodeTypeNear}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewFilterParser([]string{"name"})
+			if err := p.RegisterOperator(tt.op); (err != nil) != tt.wantErr {
+				t.Errorf("RegisterOperator() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("duplicated operator", func(t *testing.T) {
+		p := NewFilterParser([]string{"name"})
+		op := Operator{Symbol: "near", Precedence: PrecedenceComparison, NodeType: nodeTypeNear}
+		if err := p.RegisterOperator(op); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		op.Symbol = "NEAR"
+		if err := p.RegisterOperator(op); err == nil {
+			t.Errorf("expected error for duplicated operator")
+		}
+	})
+}
+
+func TestFilterParser_OperatorRules(t *testing.T) {
+	registry, err := NewFieldRegistry(FieldDef{Name: "created_at", Aliases: []string{"createdAt"}})
+	if err != nil {
+		t.Fatalf("NewFieldRegistry() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		opts    []FilterOption
+		wantErr string
+	}{
+		{
+			name:  "all operators allowed by default",
+			input: "email ~ '^a' AND name SIMILAR TO 'J%'",
+		},
+		{
+			name:    "operator disabled globally",
+			input:   "name = 'John' OR email ~ '^admin'",
+			opts:    []FilterOption{WithoutOperators(TokenOperatorRegexMatchCS, TokenOperatorSimilarTo)},
+			wantErr: "error at 1:24: operator ~ is not permitted",
+		},
+		{
+			name:    "negated operator disabled globally",
+			input:   "name NOT SIMILAR TO 'J%'",
+			opts:    []FilterOption{WithoutOperators(TokenOperatorNotSimilarTo)},
+			wantErr: "operator NOT SIMILAR TO is not permitted",
+		},
+		{
+			name:  "non negated operator still allowed",
+			input: "name SIMILAR TO 'J%'",
+			opts:  []FilterOption{WithoutOperators(TokenOperatorNotSimilarTo)},
+		},
+		{
+			name:  "global allowlist",
+			input: "name = 'John' AND age IN (1, 2)",
+			opts:  []FilterOption{WithOperators(TokenOperatorEqual, TokenOperatorIn)},
+		},
+		{
+			name:    "operator outside global allowlist",
+			input:   "name = 'John' AND age > 3",
+			opts:    []FilterOption{WithOperators(TokenOperatorEqual, TokenOperatorIn)},
+			wantErr: "operator > is not permitted",
+		},
+		{
+			name:    "IS NOT NULL outside global allowlist",
+			input:   "name IS NOT NULL",
+			opts:    []FilterOption{WithOperators(TokenOperatorIsNull)},
+			wantErr: "operator IS NOT NULL is not permitted",
+		},
+		{
+			name:  "field allowlist",
+			input: "email IN ('a@example.com', 'b@example.com') AND name ~ '^J'",
+			opts:  []FilterOption{WithFieldOperators("email", TokenOperatorEqual, TokenOperatorIn)},
+		},
+		{
+			name:    "operator outside field allowlist",
+			input:   "email ~ '^admin'",
+			opts:    []FilterOption{WithFieldOperators("email", TokenOperatorEqual, TokenOperatorIn)},
+			wantErr: "operator ~ is not permitted on field email",
+		},
+		{
+			name:    "field allowlist applies inside function calls",
+			input:   "lower(email) LIKE 'admin%'",
+			opts:    []FilterOption{WithFieldOperators("email", TokenOperatorEqual)},
+			wantErr: "operator LIKE is not permitted on field email",
+		},
+		{
+			name:    "custom operator outside field allowlist",
+			input:   "name @> 'x'",
+			opts:    []FilterOption{WithFieldOperators("name", TokenOperatorEqual)},
+			wantErr: "operator @> is not permitted on field name",
+		},
+		{
+			name:    "field allowlist applies to right-hand fields",
+			input:   "name > email",
+			opts:    []FilterOption{WithFieldOperators("email", TokenOperatorEqual)},
+			wantErr: "error at 1:6: operator > is not permitted on field email",
+		},
+		{
+			name:    "field allowlist applies to fields in lists",
+			input:   "name NOT IN ('a', email)",
+			opts:    []FilterOption{WithFieldOperators("email", TokenOperatorEqual, TokenOperatorIn)},
+			wantErr: "operator NOT IN is not permitted on field email",
+		},
+		{
+			name:  "right-hand field within allowlist",
+			input: "name = email",
+			opts:  []FilterOption{WithFieldOperators("email", TokenOperatorEqual)},
+		},
+		{
+			name:    "field allowlist given by alias",
+			input:   "created_at > '2024-01-01'",
+			opts:    []FilterOption{WithFieldRegistry(registry), WithFieldOperators("createdAt", TokenOperatorEqual)},
+			wantErr: "error at 1:12: operator > is not permitted on field created_at",
+		},
+		{
+			name:    "field allowlist given by a matching name",
+			input:   "email LIKE 'a%'",
+			opts:    []FilterOption{WithFieldMatching(MatchCaseInsensitive), WithFieldOperators("Email", TokenOperatorEqual)},
+			wantErr: "operator LIKE is not permitted on field email",
+		},
+		{
+			name:    "field allowlist on unknown field",
+			input:   "name = 'John'",
+			opts:    []FilterOption{WithFieldOperators("emial", TokenOperatorEqual)},
+			wantErr: "operators of field emial: unknown field",
+		},
+		{
+			name:    "field allowlist and global denylist",
+			input:   "email = 'a@example.com'",
+			opts:    []FilterOption{WithFieldOperators("email", TokenOperatorEqual), WithoutOperators(TokenOperatorEqual)},
+			wantErr: "operator = is not permitted",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewFilterParser([]string{"name", "email", "age"}, tt.opts...)
+			if err := p.RegisterFunction(FunctionLower); err != nil {
+				t.Fatalf("RegisterFunction() error = %v", err)
+			}
+			if err := p.RegisterOperator(Operator{Symbol: "@>", Precedence: PrecedenceComparison, NodeType: nodeTypeContains}); err != nil {
+				t.Fatalf("RegisterOperator() error = %v", err)
+			}
+
+			_, err := p.Parse(tt.input)
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("expected error containing '%s', got nil", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing '%s', got '%v'", tt.wantErr, err)
+			}
+		})
+	}
+}
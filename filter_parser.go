@@ -3,6 +3,7 @@ package qfv
 import (
 	"fmt"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 	"text/scanner"
@@ -11,42 +12,119 @@ import (
 type QFVFilterError struct {
 	Field   string
 	Message string
+	Pos     scanner.Position // Position of the error in the input, if known
 }
 
 func (e *QFVFilterError) Error() string {
+	var at string
+	if e.Pos.IsValid() {
+		at = fmt.Sprintf(" at %d:%d", e.Pos.Line, e.Pos.Column)
+	}
+
 	if e.Field != "" {
-		return fmt.Sprintf("error on field '%s': %s", e.Field, e.Message)
+		return fmt.Sprintf("error on field '%s'%s: %s", e.Field, at, e.Message)
 	}
 
-	return fmt.Sprintf("error: %s", e.Message)
+	return fmt.Sprintf("error%s: %s", at, e.Message)
 }
 
 // FilterParser parses the query parameter for filtering
 type FilterParser struct {
-	allowedFields map[string]any // any because don't allocate memory for struct{}
-	lexer         *Lexer
-	currentToken  Token
-	errors        []error
+	fields       fieldSet
+	roles        []string
+	functions    functionRegistry
+	operators    operatorRegistry
+	lexer        *Lexer
+	currentToken Token
+	errors       []error
+
+	allowLeadingWildcards bool
+	patternLimits         patternLimits
+	operatorRules         operatorRules
+
+	err        error // Invalid configuration, returned by every parse
+	limits     FilterLimits
+	limitErr   error
+	depth      int
+	predicates int
+	regexCount int
 }
 
-// NewFilterParser creates a new parser with the allowed fields
-func NewFilterParser(allowedFields []string) *FilterParser {
-	filterFields := make(map[string]any, len(allowedFields))
+// FilterOption configures a FilterParser
+type FilterOption interface {
+	applyFilter(*FilterParser)
+}
+
+// filterOption adapts a function to the FilterOption interface
+type filterOption func(*FilterParser)
+
+func (o filterOption) applyFilter(p *FilterParser) { o(p) }
+
+// WithLeadingWildcards sets whether LIKE and ILIKE patterns may start with a
+// wildcard (e.g. '%john'), which prevents the database from using an index.
+// CONTAINS and ENDS WITH match with a leading wildcard and are rejected too.
+// Leading wildcards are allowed by default.
+func WithLeadingWildcards(allowed bool) FilterOption {
+	return filterOption(func(p *FilterParser) {
+		p.allowLeadingWildcards = allowed
+	})
+}
 
-	for _, f := range allowedFields {
-		filterFields[f] = struct{}{}
+// NewFilterParser creates a new parser with the allowed fields
+func NewFilterParser(allowedFields []string, opts ...FilterOption) *FilterParser {
+	p := &FilterParser{
+		fields:                newFieldSet(allowedFields),
+		functions:             make(functionRegistry),
+		operators:             make(operatorRegistry),
+		allowLeadingWildcards: true,
 	}
 
-	return &FilterParser{
-		allowedFields: filterFields,
+	for _, opt := range opts {
+		opt.applyFilter(p)
 	}
+	p.err = p.operatorRules.resolve(&p.fields)
+
+	return p
+}
+
+// RegisterOperator extends the filter grammar with a custom binary operator
+// (e.g. tags @> 'admin', location NEAR 'Berlin')
+func (p *FilterParser) RegisterOperator(op Operator) error {
+	return p.operators.register(op)
+}
+
+// RegisterFunction allows calls to the function in filter expressions
+// (e.g. p.RegisterFunction(FunctionLower) enables lower(email) = 'x')
+func (p *FilterParser) RegisterFunction(fn Function) error {
+	return p.functions.register(fn)
 }
 
 // Parse parses the filter query and returns the AST
 func (p *FilterParser) Parse(input string) (Node, error) {
+	return p.ParseWithRoles(input)
+}
+
+// ParseWithRoles parses the filter query for a caller with the given roles,
+// which restrict the fields of the registry (see WithFieldRegistry)
+func (p *FilterParser) ParseWithRoles(input string, roles ...string) (Node, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	if p.limits.MaxInputLength > 0 && len(input) > p.limits.MaxInputLength {
+		return nil, &QFVLimitError{Limit: LimitInputLength, Max: p.limits.MaxInputLength}
+	}
+
 	p.lexer = NewLexer(input)
+	p.lexer.operators = make(map[string]TokenType, len(p.operators))
+	for tok := range p.operators {
+		p.lexer.operators[string(tok)] = tok
+	}
 	p.lexer.Parse()
+	p.roles = roles
 	p.errors = nil
+	p.limitErr = nil
+	p.depth, p.predicates, p.regexCount = 0, 0, 0
 
 	// Check for illegal tokens in the input
 	for _, token := range p.lexer.tokens {
@@ -63,6 +141,10 @@ func (p *FilterParser) Parse(input string) (Node, error) {
 
 	node := p.parseExpression()
 
+	if p.limitErr != nil {
+		return nil, p.limitErr
+	}
+
 	if len(p.errors) > 0 {
 		return nil, &QFVFilterError{Message: fmt.Sprintf("parsing errors: %v", p.errors)}
 	}
@@ -100,7 +182,16 @@ func (p *FilterParser) parseExpression() Node {
 func (p *FilterParser) parseLogicalOr() Node {
 	left := p.parseLogicalAnd()
 
-	for p.currentToken.Type == TokenOperatorOr {
+	for {
+		if op, ok := p.operators.lookup(p.currentToken, PrecedenceOr); ok {
+			left = p.parseCustomLogicalOperator(left, op, p.parseLogicalAnd)
+			continue
+		}
+
+		if p.currentToken.Type != TokenOperatorOr {
+			break
+		}
+
 		pos := p.currentToken.Pos
 		operator := p.currentToken.Type
 		p.nextToken()
@@ -120,7 +211,16 @@ func (p *FilterParser) parseLogicalOr() Node {
 func (p *FilterParser) parseLogicalAnd() Node {
 	left := p.parseComparison()
 
-	for p.currentToken.Type == TokenOperatorAnd {
+	for {
+		if op, ok := p.operators.lookup(p.currentToken, PrecedenceAnd); ok {
+			left = p.parseCustomLogicalOperator(left, op, p.parseComparison)
+			continue
+		}
+
+		if p.currentToken.Type != TokenOperatorAnd {
+			break
+		}
+
 		pos := p.currentToken.Pos
 		operator := p.currentToken.Type
 		p.nextToken()
@@ -136,13 +236,38 @@ func (p *FilterParser) parseLogicalAnd() Node {
 	return left
 }
 
+// parseCustomLogicalOperator parses a custom operator binding two expressions,
+// using parseOperand to parse the right-hand expression
+func (p *FilterParser) parseCustomLogicalOperator(left Node, op Operator, parseOperand func() Node) Node {
+	pos := p.currentToken.Pos
+	p.nextToken() // Consume operator
+	right := parseOperand()
+
+	if op.Validate != nil {
+		if err := op.Validate(left, right); err != nil {
+			p.addError(&QFVFilterError{Message: fmt.Sprintf("operator %s: %s", op.Symbol, err)})
+		}
+	}
+
+	return &CustomOperatorNode{
+		baseNode: baseNode{pos: pos},
+		Left:     left,
+		Right:    right,
+		Operator: op,
+	}
+}
+
 // parseComparison parses comparison expressions
 func (p *FilterParser) parseComparison() Node {
 	// Check for NOT operator
 	if p.currentToken.Type == TokenOperatorNot {
 		pos := p.currentToken.Pos
 		p.nextToken()
+		if !p.enter() {
+			return &LiteralNode{}
+		}
 		expr := p.parseComparison()
+		p.leave()
 		return &UnaryOperatorNode{
 			baseNode: baseNode{pos: pos},
 			Operator: TokenOperatorNot,
@@ -154,7 +279,11 @@ func (p *FilterParser) parseComparison() Node {
 	if p.currentToken.Type == TokenLPAREN {
 		pos := p.currentToken.Pos
 		p.nextToken()
+		if !p.enter() {
+			return &LiteralNode{}
+		}
 		expr := p.parseExpression()
+		p.leave()
 		if !p.expect(TokenRPAREN) {
 			p.addError(&QFVFilterError{Message: "expected closing parenthesis"})
 		}
@@ -164,145 +293,300 @@ func (p *FilterParser) parseComparison() Node {
 		}
 	}
 
+	// Every other term is a predicate, including literals (e.g. 1 = 1)
+	if !p.countPredicate(p.currentToken.Pos) {
+		return &LiteralNode{}
+	}
+
 	// Parse field comparison
-	if p.currentToken.Type == TokenIdentifier {
-		field := &IdentifierNode{
-			baseNode: baseNode{pos: p.currentToken.Pos},
-			Name:     p.currentToken.Value,
+	if p.currentToken.Type == TokenIdentifier || p.currentToken.Type == TokenQuotedIdentifier {
+		field := p.parseFieldExpression()
+		op, pos := p.predicateOperator(), p.currentToken.Pos
+		predicate := p.parsePredicate(field)
+		p.checkOperator(op, pos, predicate)
+		return predicate
+	}
+
+	// Parse literal
+	return p.parsePrimary()
+}
+
+// parsePredicate parses the operator and operands of the predicate on the field
+// Expects the current token to be the operator.
+func (p *FilterParser) parsePredicate(field Node) Node {
+	if op, ok := p.operators.lookup(p.currentToken, PrecedenceComparison); ok {
+		return p.parseCustomOperator(field, op)
+	}
+
+	// Handle different operators
+	switch p.currentToken.Type {
+	case TokenOperatorEqual, TokenOperatorNotEqual, TokenOperatorNotEqualAlias,
+		TokenOperatorLessThan, TokenOperatorLessThanOrEqualTo,
+		TokenOperatorGreaterThan, TokenOperatorGreaterThanOrEqualTo:
+		return p.parseComparisonOperator(field)
+	case TokenOperatorLike, TokenOperatorILike:
+		operator := p.currentToken.Type
+		p.nextToken() // Consume LIKE or ILIKE
+		return p.parseLikeOperator(field, operator)
+	case TokenOperatorContains, TokenOperatorStartsWith, TokenOperatorEndsWith:
+		return p.parseStringMatchOperator(field)
+	case TokenOperatorIn:
+		p.nextToken() // Consume IN
+		return p.parseInOperator(field)
+	case TokenOperatorBetween:
+		p.nextToken() // Consume BETWEEN
+		return p.parseBetweenOperator(field)
+	case TokenOperatorIsNull:
+		p.nextToken() // Consume IS
+		return p.parseIsNullOperator(field)
+	case TokenOperatorDistinct:
+		p.nextToken() // Consume DISTINCT
+		return p.parseDistinctOperator(field)
+	case TokenOperatorSimilarTo:
+		p.nextToken() // Consume SIMILAR
+		return p.parseSimilarToOperator(field)
+	case TokenOperatorRegexMatchCS, TokenOperatorNotRegexMatchCS, TokenOperatorRegexMatchCI, TokenOperatorNotRegexMatchCI:
+		opToken := p.currentToken
+		if !p.countRegex(opToken.Pos) {
+			return field
 		}
-		p.nextToken()
+		p.nextToken() // Consume regex operator
+		patternNode := p.parsePrimary()
+
+		// Check if the pattern is a string literal
+		patternLiteral, ok := patternNode.(*LiteralNode)
+		if !ok || patternLiteral.Kind != reflect.String {
+			p.addError(&QFVFilterError{Message: fmt.Sprintf("expected string pattern for regex operator %s, got %s", opToken.Type, patternNode.Type())})
+			// Return the field node or the invalid pattern node on error
+			// Returning the pattern node might give slightly better context
+			return patternNode
+		}
+		p.checkOperandType(field, patternNode)
 
-		// Check if field is allowed
-		if _, ok := p.allowedFields[field.Name]; !ok {
-			p.addError(&QFVFilterError{Field: field.Name, Message: "field not allowed"})
+		isCaseInsensitive := opToken.Type == TokenOperatorRegexMatchCI || opToken.Type == TokenOperatorNotRegexMatchCI
+		pattern := patternLiteral.Value.(string)
+		expr := pattern
+		if isCaseInsensitive {
+			expr = "(?i)" + pattern
 		}
+		if _, err := p.patternLimits.compile(pattern, expr); err != nil {
+			p.addError(&QFVFilterError{Field: inputName(field), Message: err.Error(), Pos: patternLiteral.Pos()})
+		}
+
+		return &RegexMatchNode{
+			baseNode:          baseNode{pos: opToken.Pos},
+			Field:             field,
+			Pattern:           patternNode, // Use the parsed node
+			IsNot:             opToken.Type == TokenOperatorNotRegexMatchCS || opToken.Type == TokenOperatorNotRegexMatchCI,
+			IsCaseInsensitive: isCaseInsensitive,
+		}
+	case TokenOperatorNot:
+		// Handle NOT operators (NOT IN, NOT BETWEEN, NOT LIKE, NOT SIMILAR TO, IS NOT NULL, NOT DISTINCT FROM)
+		notPos := p.currentToken.Pos
+		p.nextToken() // Consume NOT
 
-		// Handle different operators
+		var notExpr Node
 		switch p.currentToken.Type {
-		case TokenOperatorEqual, TokenOperatorNotEqual, TokenOperatorNotEqualAlias,
-			TokenOperatorLessThan, TokenOperatorLessThanOrEqualTo,
-			TokenOperatorGreaterThan, TokenOperatorGreaterThanOrEqualTo:
-			return p.parseComparisonOperator(field)
-		case TokenOperatorLike:
-			p.nextToken() // Consume LIKE
-			return p.parseLikeOperator(field)
 		case TokenOperatorIn:
 			p.nextToken() // Consume IN
-			return p.parseInOperator(field)
+			notExpr = p.parseInOperator(field)
 		case TokenOperatorBetween:
 			p.nextToken() // Consume BETWEEN
-			return p.parseBetweenOperator(field)
-		case TokenOperatorIsNull:
-			p.nextToken() // Consume IS
-			return p.parseIsNullOperator(field)
-		case TokenOperatorDistinct:
-			p.nextToken() // Consume DISTINCT
-			return p.parseDistinctOperator(field)
+			notExpr = p.parseBetweenOperator(field)
+		case TokenOperatorLike, TokenOperatorILike:
+			operator := p.currentToken.Type
+			p.nextToken() // Consume LIKE or ILIKE
+			notExpr = p.parseLikeOperator(field, operator)
+		case TokenOperatorContains, TokenOperatorStartsWith, TokenOperatorEndsWith:
+			notExpr = p.parseStringMatchOperator(field)
+			if _, ok := notExpr.(*StringMatchNode); !ok {
+				return notExpr // Return field on error
+			}
 		case TokenOperatorSimilarTo:
-			p.nextToken() // Consume SIMILAR
-			return p.parseSimilarToOperator(field)
-		case TokenOperatorRegexMatchCS, TokenOperatorNotRegexMatchCS, TokenOperatorRegexMatchCI, TokenOperatorNotRegexMatchCI:
-			opToken := p.currentToken
-			p.nextToken() // Consume regex operator
-			patternNode := p.parsePrimary()
-
-			// Check if the pattern is a string literal
-			patternLiteral, ok := patternNode.(*LiteralNode)
-			if !ok || patternLiteral.Kind != reflect.String {
-				p.addError(&QFVFilterError{Message: fmt.Sprintf("expected string pattern for regex operator %s, got %s", opToken.Type, patternNode.Type())})
-				// Return the field node or the invalid pattern node on error
-				// Returning the pattern node might give slightly better context
-				return patternNode
+			p.nextToken()                             // Consume SIMILAR
+			notExpr = p.parseSimilarToOperator(field) // Expects TO next
+		case TokenOperatorIsNull: // Handle IS NOT NULL here
+			p.nextToken() // Consume IS
+			// parseIsNullOperator handles the NOT internally now based on token sequence
+			notExpr = p.parseIsNullOperator(field)
+			// Check if parseIsNullOperator correctly identified IS NOT NULL
+			if isNullNode, ok := notExpr.(*IsNullNode); !ok || !isNullNode.IsNot {
+				// If parseIsNullOperator didn't return an IsNullNode with IsNot=true,
+				// it means the sequence wasn't "IS NOT NULL".
+				// The error would have been added inside parseIsNullOperator.
+				// We might return the field or a generic error node, but returning
+				// the result from parseIsNullOperator (which might be 'field') is consistent.
+				return notExpr // Return whatever parseIsNullOperator returned on error
 			}
-
-			return &RegexMatchNode{
-				baseNode:          baseNode{pos: opToken.Pos},
-				Field:             field,
-				Pattern:           patternNode, // Use the parsed node
-				IsNot:             opToken.Type == TokenOperatorNotRegexMatchCS || opToken.Type == TokenOperatorNotRegexMatchCI,
-				IsCaseInsensitive: opToken.Type == TokenOperatorRegexMatchCI || opToken.Type == TokenOperatorNotRegexMatchCI,
+			// If it was IS NOT NULL, we don't need to wrap it again
+			return notExpr
+		case TokenOperatorDistinct: // Handle NOT DISTINCT FROM here
+			p.nextToken() // Consume DISTINCT
+			// parseDistinctOperator handles the FROM internally
+			notExpr = p.parseDistinctOperator(field)
+			// Similar to IS NOT NULL, check if parseDistinctOperator failed
+			if _, ok := notExpr.(*DistinctNode); !ok {
+				return notExpr // Return error node or field
 			}
-		case TokenOperatorNot:
-			// Handle NOT operators (NOT IN, NOT BETWEEN, NOT LIKE, NOT SIMILAR TO, IS NOT NULL, NOT DISTINCT FROM)
-			notPos := p.currentToken.Pos
-			p.nextToken() // Consume NOT
-
-			var notExpr Node
-			switch p.currentToken.Type {
-			case TokenOperatorIn:
-				p.nextToken() // Consume IN
-				notExpr = p.parseInOperator(field)
-			case TokenOperatorBetween:
-				p.nextToken() // Consume BETWEEN
-				notExpr = p.parseBetweenOperator(field)
-			case TokenOperatorLike:
-				p.nextToken() // Consume LIKE
-				notExpr = p.parseLikeOperator(field)
-			case TokenOperatorSimilarTo:
-				p.nextToken()                             // Consume SIMILAR
-				notExpr = p.parseSimilarToOperator(field) // Expects TO next
-			case TokenOperatorIsNull: // Handle IS NOT NULL here
-				p.nextToken() // Consume IS
-				// parseIsNullOperator handles the NOT internally now based on token sequence
-				notExpr = p.parseIsNullOperator(field)
-				// Check if parseIsNullOperator correctly identified IS NOT NULL
-				if isNullNode, ok := notExpr.(*IsNullNode); !ok || !isNullNode.IsNot {
-					// If parseIsNullOperator didn't return an IsNullNode with IsNot=true,
-					// it means the sequence wasn't "IS NOT NULL".
-					// The error would have been added inside parseIsNullOperator.
-					// We might return the field or a generic error node, but returning
-					// the result from parseIsNullOperator (which might be 'field') is consistent.
-					return notExpr // Return whatever parseIsNullOperator returned on error
-				}
-				// If it was IS NOT NULL, we don't need to wrap it again
-				return notExpr
-			case TokenOperatorDistinct: // Handle NOT DISTINCT FROM here
-				p.nextToken() // Consume DISTINCT
-				// parseDistinctOperator handles the FROM internally
-				notExpr = p.parseDistinctOperator(field)
-				// Similar to IS NOT NULL, check if parseDistinctOperator failed
-				if _, ok := notExpr.(*DistinctNode); !ok {
-					return notExpr // Return error node or field
-				}
-			default:
-				p.addError(&QFVFilterError{Message: fmt.Sprintf("unexpected token after NOT: %s", p.currentToken.Type)})
-				// If NOT is followed by something unexpected, return a unary NOT node with the field
-				// This might not be the most robust error handling, but fits the previous pattern.
-				return &UnaryOperatorNode{
-					baseNode: baseNode{pos: notPos},
-					Operator: TokenOperatorNot,
-					X:        field, // Apply NOT to the field itself? Or error?
-				}
+		default:
+			p.addError(&QFVFilterError{Message: fmt.Sprintf("unexpected token after NOT: %s", p.currentToken.Type)})
+			// If NOT is followed by something unexpected, return a unary NOT node with the field
+			// This might not be the most robust error handling, but fits the previous pattern.
+			return &UnaryOperatorNode{
+				baseNode: baseNode{pos: notPos},
+				Operator: TokenOperatorNot,
+				X:        field, // Apply NOT to the field itself? Or error?
 			}
+		}
 
-			// Wrap the parsed expression (LIKE, IN, BETWEEN, SIMILAR TO, DISTINCT) in a UnaryOperatorNode(NOT)
-			// Skip wrapping if it was handled internally (IS NOT NULL)
-			if _, isIsNull := notExpr.(*IsNullNode); !isIsNull {
-				return &UnaryOperatorNode{
-					baseNode: baseNode{pos: notPos},
-					Operator: TokenOperatorNot,
-					X:        notExpr,
-				}
+		// Wrap the parsed expression (LIKE, IN, BETWEEN, SIMILAR TO, DISTINCT) in a UnaryOperatorNode(NOT)
+		// Skip wrapping if it was handled internally (IS NOT NULL)
+		if _, isIsNull := notExpr.(*IsNullNode); !isIsNull {
+			return &UnaryOperatorNode{
+				baseNode: baseNode{pos: notPos},
+				Operator: TokenOperatorNot,
+				X:        notExpr,
 			}
-			// For IS NOT NULL, return the node directly as IsNot is set inside
-			return notExpr
+		}
+		// For IS NOT NULL, return the node directly as IsNot is set inside
+		return notExpr
 
-		default:
-			p.addError(&QFVFilterError{Field: field.Name, Message: "unexpected token after field"})
-			return field
+	default:
+		p.addError(&QFVFilterError{Field: inputName(field), Message: "unexpected token after field"})
+		return field
+	}
+}
+
+// parseIdentifier parses a field name and checks it against the allowed fields
+func (p *FilterParser) parseIdentifier() *IdentifierNode {
+	field := &IdentifierNode{
+		baseNode: baseNode{pos: p.currentToken.Pos},
+		Name:     p.currentToken.Value,
+	}
+	p.nextToken()
+
+	// Check if field is allowed
+	name, ok := p.fields.resolve(field.Name, p.roles)
+	if !ok {
+		p.addError(&QFVFilterError{Field: field.Name, Message: "field not allowed"})
+		return field
+	}
+
+	if name != field.Name {
+		field.Name, field.Alias = name, field.Name
+	}
+
+	if path := ParseFieldPath(field.Name); path.IsNested() {
+		field.Path = path
+	}
+
+	return field
+}
+
+// inputName returns the field expression as written in the input, so errors
+// show the aliases used by the client rather than the canonical names
+func inputName(node Node) string {
+	switch n := node.(type) {
+	case *IdentifierNode:
+		return n.Original()
+	case *FunctionCallNode:
+		args := make([]string, len(n.Args))
+		for i, arg := range n.Args {
+			args[i] = inputName(arg)
 		}
+		return fmt.Sprintf("%s(%s)", n.Name, strings.Join(args, ", "))
+	default:
+		return node.String()
+	}
+}
+
+// parseFieldExpression parses a field or a function call on fields (e.g. lower(email))
+func (p *FilterParser) parseFieldExpression() Node {
+	if p.currentToken.Type == TokenIdentifier && p.lexer.Peek().Type == TokenLPAREN {
+		return p.parseFunctionCall()
+	}
+
+	return p.parseIdentifier()
+}
+
+// parseFunctionCall parses a call to a registered function
+// Expects the current token to be the function name.
+func (p *FilterParser) parseFunctionCall() Node {
+	pos := p.currentToken.Pos
+	name := p.currentToken.Value
+	fn, ok := p.functions[strings.ToLower(name)]
+	if !ok {
+		p.addError(&QFVFilterError{Field: name, Message: "function not allowed"})
+	}
+	p.nextToken() // Consume function name
+	p.nextToken() // Consume (
+
+	if !p.enter() {
+		return &FunctionCallNode{baseNode: baseNode{pos: pos}, Name: name}
+	}
+
+	var args []Node
+	if p.currentToken.Type != TokenRPAREN {
+		args = append(args, p.parseOperand())
+		for p.currentToken.Type == TokenComma {
+			p.nextToken()
+			args = append(args, p.parseOperand())
+		}
+	}
+	p.leave()
+
+	if !p.expect(TokenRPAREN) {
+		p.addError(&QFVFilterError{Field: name, Message: "expected closing parenthesis after function arguments"})
+	}
+
+	if !ok {
+		return &FunctionCallNode{baseNode: baseNode{pos: pos}, Name: name, Args: args}
+	}
+
+	if !slices.ContainsFunc(args, func(arg Node) bool { return len(referencedFields(arg)) > 0 }) {
+		// Calls on literals only are constant (e.g. lower('a') = 'a'), like in sorts
+		p.addError(&QFVFilterError{Field: fn.Name, Message: "function call must reference a field", Pos: pos})
+	}
+
+	for _, msg := range fn.check(args) {
+		p.addError(&QFVFilterError{Field: fn.Name, Message: msg})
+	}
+
+	return &FunctionCallNode{
+		baseNode: baseNode{pos: pos},
+		Name:     fn.Name,
+		Args:     args,
+		Function: fn,
+	}
+}
+
+// parseOperand parses the value side of an operator, which is either
+// an allowed field (e.g. updated_at > created_at), a function call or a literal
+func (p *FilterParser) parseOperand() Node {
+	switch {
+	case p.currentToken.Type == TokenQuotedIdentifier,
+		p.currentToken.Type == TokenIdentifier && strings.ToUpper(p.currentToken.Value) != "NULL":
+		return p.parseFieldExpression()
 	}
 
-	// Parse literal
 	return p.parsePrimary()
 }
 
+// checkOperandType checks that the operand can be compared with the field expression
+func (p *FilterParser) checkOperandType(field Node, operand Node) {
+	fieldType := valueTypeOf(field)
+	if operandType := valueTypeOf(operand); !fieldType.accepts(operandType) {
+		p.addError(&QFVFilterError{Field: inputName(field), Message: fmt.Sprintf("cannot compare %s with %s", fieldType, operandType)})
+	}
+}
+
 // parseComparisonOperator parses comparison operators (=, <>, !=, <, <=, >, >=)
 func (p *FilterParser) parseComparisonOperator(field Node) Node {
 	pos := p.currentToken.Pos
 	operator := p.currentToken.Type
 	p.nextToken()
-	right := p.parsePrimary()
+	right := p.parseOperand()
+	p.checkOperandType(field, right)
 	return &BinaryOperatorNode{
 		baseNode: baseNode{pos: pos},
 		Left:     field,
@@ -311,6 +595,73 @@ func (p *FilterParser) parseComparisonOperator(field Node) Node {
 	}
 }
 
+// parseCustomOperator parses a custom operator binding a field and a value
+// Expects the current token to be the operator.
+func (p *FilterParser) parseCustomOperator(field Node, op Operator) Node {
+	pos := p.currentToken.Pos
+	p.nextToken() // Consume operator
+
+	var right Node
+	var values []Node
+	if op.List {
+		list := p.parseList()
+		right, values = list, list.Values
+	} else {
+		right = p.parseOperand()
+		values = []Node{right}
+	}
+
+	if op.Operand != "" {
+		for _, v := range values {
+			if valueType := valueTypeOf(v); !op.Operand.accepts(valueType) {
+				p.addError(&QFVFilterError{Field: inputName(field), Message: fmt.Sprintf("operator %s expects %s, got %s", op.Symbol, op.Operand, valueType)})
+			}
+		}
+	}
+
+	if op.Validate != nil {
+		if err := op.Validate(field, right); err != nil {
+			p.addError(&QFVFilterError{Field: inputName(field), Message: fmt.Sprintf("operator %s: %s", op.Symbol, err)})
+		}
+	}
+
+	return &CustomOperatorNode{
+		baseNode: baseNode{pos: pos},
+		Left:     field,
+		Right:    right,
+		Operator: op,
+	}
+}
+
+// parseList parses a parenthesized, comma separated list of values
+// Expects the current token to be LPAREN.
+func (p *FilterParser) parseList() *ListNode {
+	list := &ListNode{baseNode: baseNode{pos: p.currentToken.Pos}}
+	if !p.expect(TokenLPAREN) {
+		return list
+	}
+
+	if p.currentToken.Type == TokenRPAREN {
+		p.addError(&QFVFilterError{Message: "expected at least one value in list"})
+	} else {
+		list.Values = append(list.Values, p.parseOperand())
+	}
+
+	for p.currentToken.Type == TokenComma {
+		p.nextToken()
+		list.Values = append(list.Values, p.parseOperand())
+		if !p.checkListSize(len(list.Values), p.currentToken.Pos) {
+			return list
+		}
+	}
+
+	if !p.expect(TokenRPAREN) {
+		p.addError(&QFVFilterError{Message: "expected closing parenthesis after list values"})
+	}
+
+	return list
+}
+
 // parseSimilarToOperator parses SIMILAR TO operator
 // Expects the current token to be TO after SIMILAR was consumed.
 func (p *FilterParser) parseSimilarToOperator(field Node) Node {
@@ -320,7 +671,26 @@ func (p *FilterParser) parseSimilarToOperator(field Node) Node {
 		return field // Return field on error
 	}
 	p.nextToken() // Consume TO
+	if !p.countRegex(pos) {
+		return field
+	}
 	pattern := p.parsePrimary()
+
+	patternLiteral, ok := pattern.(*LiteralNode)
+	if !ok || patternLiteral.Kind != reflect.String {
+		p.addError(&QFVFilterError{Message: fmt.Sprintf("expected string pattern for SIMILAR TO operator, got %s", pattern.Type())})
+		return field // Return field on error
+	}
+	p.checkOperandType(field, pattern)
+
+	expr, err := SimilarToRegexp(patternLiteral.Value.(string))
+	if err == nil {
+		_, err = p.patternLimits.compile(patternLiteral.Value.(string), expr)
+	}
+	if err != nil {
+		p.addError(&QFVFilterError{Field: inputName(field), Message: err.Error(), Pos: patternLiteral.Pos()})
+	}
+
 	return &SimilarToNode{
 		baseNode: baseNode{pos: pos},
 		Field:    field,
@@ -329,16 +699,82 @@ func (p *FilterParser) parseSimilarToOperator(field Node) Node {
 	}
 }
 
-// parseLikeOperator parses LIKE operator
-// Expects the current token to be the pattern after LIKE was consumed.
-func (p *FilterParser) parseLikeOperator(field Node) Node {
+// parseLikeOperator parses LIKE and ILIKE operators with an optional ESCAPE clause
+// Expects the current token to be the pattern after LIKE or ILIKE was consumed.
+func (p *FilterParser) parseLikeOperator(field Node, operator TokenType) Node {
 	pos := p.lexer.Current().Pos // Use position of LIKE token (already consumed)
 	pattern := p.parsePrimary()
-	return &BinaryOperatorNode{
-		baseNode: baseNode{pos: pos},
-		Left:     field,
-		Right:    pattern,
-		Operator: TokenOperatorLike,
+
+	patternLiteral, ok := pattern.(*LiteralNode)
+	if !ok || patternLiteral.Kind != reflect.String {
+		p.addError(&QFVFilterError{Message: fmt.Sprintf("expected string pattern for %s operator, got %s", operator, pattern.Type())})
+		return field // Return field on error
+	}
+	p.checkOperandType(field, pattern)
+
+	node := &LikeNode{
+		baseNode:          baseNode{pos: pos},
+		Field:             field,
+		Pattern:           pattern,
+		IsNot:             false, // NOT is handled by parseComparison
+		IsCaseInsensitive: operator == TokenOperatorILike,
+	}
+
+	// Check for ESCAPE (treated as identifier by lexer)
+	if p.currentToken.Type == TokenIdentifier && strings.ToUpper(p.currentToken.Value) == "ESCAPE" {
+		p.nextToken() // Consume ESCAPE
+		escape := p.parsePrimary()
+		escapeLiteral, ok := escape.(*LiteralNode)
+		if !ok || escapeLiteral.Kind != reflect.String || len([]rune(escapeLiteral.Value.(string))) != 1 {
+			p.addError(&QFVFilterError{Message: "expected single character string after ESCAPE"})
+			return field // Return field on error
+		}
+		node.Escape = escapeLiteral.Value.(string)
+	}
+
+	value := patternLiteral.Value.(string)
+	if node.Escape != "" && strings.HasSuffix(strings.ReplaceAll(value, node.Escape+node.Escape, ""), node.Escape) {
+		p.addError(&QFVFilterError{Message: fmt.Sprintf("pattern %s ends with escape character", patternLiteral.Text)})
+	}
+
+	if !p.allowLeadingWildcards && (strings.HasPrefix(value, "%") || strings.HasPrefix(value, "_")) {
+		p.addError(&QFVFilterError{Message: fmt.Sprintf("pattern %s starts with a wildcard", patternLiteral.Text)})
+	}
+
+	return node
+}
+
+// parseStringMatchOperator parses CONTAINS, STARTS WITH and ENDS WITH operators
+// Expects the current token to be the operator.
+func (p *FilterParser) parseStringMatchOperator(field Node) Node {
+	opToken := p.currentToken
+	p.nextToken() // Consume CONTAINS, STARTS or ENDS
+
+	if opToken.Type != TokenOperatorContains {
+		if p.currentToken.Type != TokenIdentifier || strings.ToUpper(p.currentToken.Value) != "WITH" {
+			p.addError(&QFVFilterError{Message: fmt.Sprintf("expected WITH after %s", strings.ToUpper(opToken.Value))})
+			return field // Return field on error
+		}
+		p.nextToken() // Consume WITH
+	}
+
+	value := p.parsePrimary()
+	if literal, ok := value.(*LiteralNode); !ok || literal.Kind != reflect.String {
+		p.addError(&QFVFilterError{Message: fmt.Sprintf("expected string value for %s operator, got %s", opToken.Type, value.Type())})
+	}
+	p.checkOperandType(field, value)
+
+	if !p.allowLeadingWildcards && opToken.Type != TokenOperatorStartsWith {
+		// CONTAINS and ENDS WITH are rendered as patterns starting with %
+		p.addError(&QFVFilterError{Message: fmt.Sprintf("operator %s matches with a leading wildcard", opToken.Type), Pos: opToken.Pos})
+	}
+
+	return &StringMatchNode{
+		baseNode: baseNode{pos: opToken.Pos},
+		Field:    field,
+		Value:    value,
+		Operator: opToken.Type,
+		IsNot:    false, // NOT is handled by parseComparison
 	}
 }
 
@@ -356,7 +792,7 @@ func (p *FilterParser) parseInOperator(field Node) Node {
 	if p.currentToken.Type == TokenRPAREN {
 		p.addError(&QFVFilterError{Message: "expected at least one value after IN ("})
 	} else {
-		values = append(values, p.parsePrimary())
+		values = append(values, p.parseOperand())
 	}
 
 	// Parse additional values
@@ -366,13 +802,20 @@ func (p *FilterParser) parseInOperator(field Node) Node {
 			p.addError(&QFVFilterError{Message: "unexpected closing parenthesis after comma in IN list"})
 			break
 		}
-		values = append(values, p.parsePrimary())
+		values = append(values, p.parseOperand())
+		if !p.checkListSize(len(values), p.currentToken.Pos) {
+			return field
+		}
 	}
 
 	if !p.expect(TokenRPAREN) {
 		p.addError(&QFVFilterError{Message: "expected closing parenthesis after IN values"})
 	}
 
+	for _, v := range values {
+		p.checkOperandType(field, v)
+	}
+
 	return &InNode{
 		baseNode: baseNode{pos: pos},
 		Field:    field,
@@ -385,14 +828,16 @@ func (p *FilterParser) parseInOperator(field Node) Node {
 // Expects the current token to be the lower bound after BETWEEN was consumed.
 func (p *FilterParser) parseBetweenOperator(field Node) Node {
 	pos := p.lexer.Current().Pos // Use position of BETWEEN token (already consumed)
-	lower := p.parsePrimary()
+	lower := p.parseOperand()
 
 	if !p.expect(TokenOperatorAnd) {
 		p.addError(&QFVFilterError{Message: "expected AND in BETWEEN expression"})
 		return field
 	}
 
-	upper := p.parsePrimary()
+	upper := p.parseOperand()
+	p.checkOperandType(field, lower)
+	p.checkOperandType(field, upper)
 
 	return &BetweenNode{
 		baseNode: baseNode{pos: pos},
@@ -458,51 +903,10 @@ func (p *FilterParser) parseDistinctOperator(field Node) Node {
 // parsePrimary parses primary expressions (literals)
 func (p *FilterParser) parsePrimary() Node {
 	switch p.currentToken.Type {
-	case TokenString:
-		node := &LiteralNode{
-			baseNode: baseNode{pos: p.currentToken.Pos},
-			Value:    strings.Trim(p.currentToken.Value, "'"),
-			Kind:     reflect.String,
-			Text:     p.currentToken.Value,
-		}
-		p.nextToken()
-		return node
-
-	case TokenInt:
-		val, err := strconv.ParseInt(p.currentToken.Value, 10, 64)
+	case TokenString, TokenInt, TokenFloat, TokenBoolean:
+		node, err := newTokenLiteral(p.currentToken)
 		if err != nil {
-			p.addError(&QFVFilterError{Message: fmt.Sprintf("invalid integer: %s", p.currentToken.Value)})
-		}
-		node := &LiteralNode{
-			baseNode: baseNode{pos: p.currentToken.Pos},
-			Value:    val,
-			Kind:     reflect.Int64,
-			Text:     p.currentToken.Value,
-		}
-		p.nextToken()
-		return node
-
-	case TokenFloat:
-		val, err := strconv.ParseFloat(p.currentToken.Value, 64)
-		if err != nil {
-			p.addError(&QFVFilterError{Message: fmt.Sprintf("invalid float: %s", p.currentToken.Value)})
-		}
-		node := &LiteralNode{
-			baseNode: baseNode{pos: p.currentToken.Pos},
-			Value:    val,
-			Kind:     reflect.Float64,
-			Text:     p.currentToken.Value,
-		}
-		p.nextToken()
-		return node
-
-	case TokenBoolean:
-		val := strings.ToUpper(p.currentToken.Value) == "TRUE" || strings.ToUpper(p.currentToken.Value) == "YES"
-		node := &LiteralNode{
-			baseNode: baseNode{pos: p.currentToken.Pos},
-			Value:    val,
-			Kind:     reflect.Bool,
-			Text:     p.currentToken.Value,
+			p.addError(&QFVFilterError{Message: err.Error()})
 		}
 		p.nextToken()
 		return node
@@ -519,3 +923,33 @@ func (p *FilterParser) parsePrimary() Node {
 		}
 	}
 }
+
+// newTokenLiteral returns the literal node for a string, number or boolean token.
+// The node is returned along with the error for malformed numbers.
+func newTokenLiteral(tok Token) (*LiteralNode, error) {
+	node := &LiteralNode{baseNode: baseNode{pos: tok.Pos}, Text: tok.Value}
+
+	switch tok.Type {
+	case TokenString:
+		node.Value, node.Kind = strings.Trim(tok.Value, "'"), reflect.String
+	case TokenInt:
+		val, err := strconv.ParseInt(tok.Value, 10, 64)
+		node.Value, node.Kind = val, reflect.Int64
+		if err != nil {
+			return node, fmt.Errorf("invalid integer: %s", tok.Value)
+		}
+	case TokenFloat:
+		val, err := strconv.ParseFloat(tok.Value, 64)
+		node.Value, node.Kind = val, reflect.Float64
+		if err != nil {
+			return node, fmt.Errorf("invalid float: %s", tok.Value)
+		}
+	case TokenBoolean:
+		upper := strings.ToUpper(tok.Value)
+		node.Value, node.Kind = upper == "TRUE" || upper == "YES", reflect.Bool
+	default:
+		return node, fmt.Errorf("unexpected token: %s", tok.Type)
+	}
+
+	return node, nil
+}
@@ -2,6 +2,7 @@ package qfv
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -288,25 +289,25 @@ func TestFilterParser_Parse(t *testing.T) {
 			allowedFields: []string{"name", "age", "status"},
 			wantErr:       false,
 			checkNode: func(t *testing.T, node Node) {
-				binOp, ok := node.(*BinaryOperatorNode)
+				like, ok := node.(*LikeNode)
 				if !ok {
-					t.Fatalf("expected BinaryOperatorNode, got %T", node)
+					t.Fatalf("expected LikeNode, got %T", node)
 				}
-				if binOp.Operator != TokenOperatorLike {
-					t.Errorf("expected LIKE operator, got %s", binOp.Operator)
+				if like.IsNot || like.IsCaseInsensitive {
+					t.Errorf("expected IsNot and IsCaseInsensitive to be false")
 				}
 
-				field, ok := binOp.Left.(*IdentifierNode)
+				field, ok := like.Field.(*IdentifierNode)
 				if !ok {
-					t.Fatalf("expected IdentifierNode for left operand, got %T", binOp.Left)
+					t.Fatalf("expected IdentifierNode for field, got %T", like.Field)
 				}
 				if field.Name != "name" {
 					t.Errorf("expected field name 'name', got %s", field.Name)
 				}
 
-				pattern, ok := binOp.Right.(*LiteralNode)
+				pattern, ok := like.Pattern.(*LiteralNode)
 				if !ok {
-					t.Fatalf("expected LiteralNode for right operand, got %T", binOp.Right)
+					t.Fatalf("expected LiteralNode for pattern, got %T", like.Pattern)
 				}
 				if pattern.Value != "%John%" {
 					t.Errorf("expected pattern '%%John%%', got %v", pattern.Value)
@@ -327,12 +328,12 @@ func TestFilterParser_Parse(t *testing.T) {
 					t.Errorf("expected NOT operator, got %s", unaryOp.Operator)
 				}
 
-				likeExpr, ok := unaryOp.X.(*BinaryOperatorNode)
+				likeExpr, ok := unaryOp.X.(*LikeNode)
 				if !ok {
-					t.Fatalf("expected BinaryOperatorNode for NOT operand, got %T", unaryOp.X)
+					t.Fatalf("expected LikeNode for NOT operand, got %T", unaryOp.X)
 				}
-				if likeExpr.Operator != TokenOperatorLike { // Check the inner node is LIKE
-					t.Errorf("expected LIKE operator in the inner node, got %s", likeExpr.Operator)
+				if likeExpr.IsNot { // The inner LikeNode should have IsNot=false
+					t.Errorf("expected IsNot to be false in the LikeNode")
 				}
 			},
 		},
@@ -645,6 +646,251 @@ func TestFilterParser_Parse(t *testing.T) {
 			allowedFields: []string{"name"},
 			wantErr:       true, // Expect error because pattern should be string
 		},
+		// ---- String Operator Tests ----
+		{
+			name:          "CONTAINS operator",
+			input:         "name CONTAINS '50%_off'",
+			allowedFields: []string{"name"},
+			wantErr:       false,
+			checkNode: func(t *testing.T, node Node) {
+				match, ok := node.(*StringMatchNode)
+				if !ok {
+					t.Fatalf("expected StringMatchNode, got %T", node)
+				}
+				if match.Type() != NodeTypeContains {
+					t.Errorf("expected node type %s, got %s", NodeTypeContains, match.Type())
+				}
+				value, ok := match.Value.(*LiteralNode)
+				if !ok {
+					t.Fatalf("expected LiteralNode for value, got %T", match.Value)
+				}
+				if value.Value != "50%_off" {
+					t.Errorf("expected value '50%%_off', got %v", value.Value)
+				}
+			},
+		},
+		{
+			name:          "STARTS WITH operator",
+			input:         "name starts with 'Jo'",
+			allowedFields: []string{"name"},
+			wantErr:       false,
+			checkNode: func(t *testing.T, node Node) {
+				match, ok := node.(*StringMatchNode)
+				if !ok {
+					t.Fatalf("expected StringMatchNode, got %T", node)
+				}
+				if match.Type() != NodeTypeStartsWith {
+					t.Errorf("expected node type %s, got %s", NodeTypeStartsWith, match.Type())
+				}
+			},
+		},
+		{
+			name:          "NOT ENDS WITH operator",
+			input:         "name NOT ENDS WITH 'hn'",
+			allowedFields: []string{"name"},
+			wantErr:       false,
+			checkNode: func(t *testing.T, node Node) {
+				unaryOp, ok := node.(*UnaryOperatorNode)
+				if !ok {
+					t.Fatalf("expected UnaryOperatorNode, got %T", node)
+				}
+				match, ok := unaryOp.X.(*StringMatchNode)
+				if !ok {
+					t.Fatalf("expected StringMatchNode for NOT operand, got %T", unaryOp.X)
+				}
+				if match.Type() != NodeTypeEndsWith {
+					t.Errorf("expected node type %s, got %s", NodeTypeEndsWith, match.Type())
+				}
+			},
+		},
+		{
+			name:          "Syntax error - STARTS without WITH",
+			input:         "name STARTS 'Jo'",
+			allowedFields: []string{"name"},
+			wantErr:       true,
+		},
+		{
+			name:          "Syntax error - CONTAINS with non-string value",
+			input:         "name CONTAINS 42",
+			allowedFields: []string{"name"},
+			wantErr:       true,
+		},
+		{
+			name:          "Syntax error - CONTAINS with field value",
+			input:         "name CONTAINS nickname",
+			allowedFields: []string{"name", "nickname"},
+			wantErr:       true,
+		},
+		{
+			name:          "ILIKE operator",
+			input:         "name ILIKE 'j%'",
+			allowedFields: []string{"name"},
+			wantErr:       false,
+			checkNode: func(t *testing.T, node Node) {
+				like, ok := node.(*LikeNode)
+				if !ok {
+					t.Fatalf("expected LikeNode, got %T", node)
+				}
+				if !like.IsCaseInsensitive {
+					t.Errorf("expected IsCaseInsensitive to be true")
+				}
+			},
+		},
+		{
+			name:          "NOT ILIKE operator",
+			input:         "name NOT ILIKE 'j%'",
+			allowedFields: []string{"name"},
+			wantErr:       false,
+			checkNode: func(t *testing.T, node Node) {
+				unaryOp, ok := node.(*UnaryOperatorNode)
+				if !ok {
+					t.Fatalf("expected UnaryOperatorNode, got %T", node)
+				}
+				likeExpr, ok := unaryOp.X.(*LikeNode)
+				if !ok || !likeExpr.IsCaseInsensitive {
+					t.Errorf("expected ILIKE in the inner node, got %v", unaryOp.X)
+				}
+			},
+		},
+		// ---- LIKE Pattern Tests ----
+		{
+			name:          "LIKE with ESCAPE",
+			input:         "name LIKE '50!%%' ESCAPE '!'",
+			allowedFields: []string{"name"},
+			wantErr:       false,
+			checkNode: func(t *testing.T, node Node) {
+				like, ok := node.(*LikeNode)
+				if !ok {
+					t.Fatalf("expected LikeNode, got %T", node)
+				}
+				if like.Escape != "!" {
+					t.Errorf("expected escape '!', got %q", like.Escape)
+				}
+				if like.String() != "name LIKE '50!%%' ESCAPE '!'" {
+					t.Errorf("unexpected string representation: %s", like.String())
+				}
+			},
+		},
+		{
+			name:          "NOT LIKE with lowercase escape",
+			input:         "name NOT LIKE 'a\\_%' escape '\\' AND name <> 'b'",
+			allowedFields: []string{"name"},
+			wantErr:       false,
+		},
+		{
+			name:          "Syntax error - LIKE with integer pattern",
+			input:         "name LIKE 42",
+			allowedFields: []string{"name"},
+			wantErr:       true,
+		},
+		{
+			name:          "Syntax error - LIKE with field pattern",
+			input:         "name LIKE nickname",
+			allowedFields: []string{"name", "nickname"},
+			wantErr:       true,
+		},
+		{
+			name:          "Syntax error - ESCAPE with more than one character",
+			input:         "name LIKE 'a%' ESCAPE '!!'",
+			allowedFields: []string{"name"},
+			wantErr:       true,
+		},
+		{
+			name:          "Syntax error - ESCAPE without value",
+			input:         "name LIKE 'a%' ESCAPE",
+			allowedFields: []string{"name"},
+			wantErr:       true,
+		},
+		{
+			name:          "Syntax error - pattern ending with escape character",
+			input:         "name LIKE 'a%!' ESCAPE '!'",
+			allowedFields: []string{"name"},
+			wantErr:       true,
+		},
+		// ---- Field-to-field Tests ----
+		{
+			name:          "field to field comparison",
+			input:         "updated_at > created_at",
+			allowedFields: []string{"created_at", "updated_at"},
+			wantErr:       false,
+			checkNode: func(t *testing.T, node Node) {
+				binOp, ok := node.(*BinaryOperatorNode)
+				if !ok {
+					t.Fatalf("expected BinaryOperatorNode, got %T", node)
+				}
+				if binOp.Operator != TokenOperatorGreaterThan {
+					t.Errorf("expected > operator, got %s", binOp.Operator)
+				}
+
+				right, ok := binOp.Right.(*IdentifierNode)
+				if !ok {
+					t.Fatalf("expected IdentifierNode for right operand, got %T", binOp.Right)
+				}
+				if right.Name != "created_at" {
+					t.Errorf("expected field name 'created_at', got %s", right.Name)
+				}
+			},
+		},
+		{
+			name:          "field to field comparison, right field not allowed",
+			input:         "updated_at > deleted_at",
+			allowedFields: []string{"created_at", "updated_at"},
+			wantErr:       true,
+		},
+		{
+			name:          "BETWEEN with field bounds",
+			input:         "age BETWEEN min_age AND 30",
+			allowedFields: []string{"age", "min_age"},
+			wantErr:       false,
+			checkNode: func(t *testing.T, node Node) {
+				between, ok := node.(*BetweenNode)
+				if !ok {
+					t.Fatalf("expected BetweenNode, got %T", node)
+				}
+				if _, ok := between.Lower.(*IdentifierNode); !ok {
+					t.Errorf("expected IdentifierNode for lower bound, got %T", between.Lower)
+				}
+				if _, ok := between.Upper.(*LiteralNode); !ok {
+					t.Errorf("expected LiteralNode for upper bound, got %T", between.Upper)
+				}
+			},
+		},
+		{
+			name:          "BETWEEN with field bound not allowed",
+			input:         "age BETWEEN 20 AND max_age",
+			allowedFields: []string{"age"},
+			wantErr:       true,
+		},
+		{
+			name:          "IN with field values",
+			input:         "name IN ('John', nickname)",
+			allowedFields: []string{"name", "nickname"},
+			wantErr:       false,
+			checkNode: func(t *testing.T, node Node) {
+				inNode, ok := node.(*InNode)
+				if !ok {
+					t.Fatalf("expected InNode, got %T", node)
+				}
+				if len(inNode.Values) != 2 {
+					t.Fatalf("expected 2 values in IN, got %d", len(inNode.Values))
+				}
+				if _, ok := inNode.Values[1].(*IdentifierNode); !ok {
+					t.Errorf("expected IdentifierNode for second value, got %T", inNode.Values[1])
+				}
+			},
+		},
+		{
+			name:          "IN with field value not allowed",
+			input:         "name IN ('John', nickname)",
+			allowedFields: []string{"name"},
+			wantErr:       true,
+		},
+		{
+			name:          "comparison with NULL is still rejected",
+			input:         "name = NULL",
+			allowedFields: []string{"name"},
+			wantErr:       true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -663,3 +909,257 @@ func TestFilterParser_Parse(t *testing.T) {
 		})
 	}
 }
+
+func TestFilterParser_Functions(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantErr   bool
+		checkNode func(t *testing.T, node Node)
+	}{
+		{
+			name:    "lower on the left side",
+			input:   "lower(email) = 'john@example.com'",
+			wantErr: false,
+			checkNode: func(t *testing.T, node Node) {
+				binOp, ok := node.(*BinaryOperatorNode)
+				if !ok {
+					t.Fatalf("expected BinaryOperatorNode, got %T", node)
+				}
+
+				call, ok := binOp.Left.(*FunctionCallNode)
+				if !ok {
+					t.Fatalf("expected FunctionCallNode for left operand, got %T", binOp.Left)
+				}
+				if call.Name != "lower" {
+					t.Errorf("expected function name 'lower', got %s", call.Name)
+				}
+				if len(call.Args) != 1 {
+					t.Fatalf("expected 1 argument, got %d", len(call.Args))
+				}
+				if field, ok := call.Args[0].(*IdentifierNode); !ok || field.Name != "email" {
+					t.Errorf("expected IdentifierNode 'email' as argument, got %v", call.Args[0])
+				}
+			},
+		},
+		{
+			name:    "function name is case-insensitive",
+			input:   "LOWER(email) = 'john@example.com'",
+			wantErr: false,
+			checkNode: func(t *testing.T, node Node) {
+				if got := node.String(); got != "(lower(email) = 'john@example.com')" {
+					t.Errorf("unexpected string representation: %s", got)
+				}
+			},
+		},
+		{
+			name:    "length compared with a number",
+			input:   "length(name) > 3",
+			wantErr: false,
+		},
+		{
+			name:    "function on the right side",
+			input:   "name = upper(email)",
+			wantErr: false,
+		},
+		{
+			name:    "nested calls",
+			input:   "length(lower(name)) BETWEEN 3 AND 10",
+			wantErr: false,
+		},
+		{
+			name:    "date_trunc with unit",
+			input:   "date_trunc('day', created_at) = '2023-01-01'",
+			wantErr: false,
+		},
+		{
+			name:    "date_trunc with unknown unit",
+			input:   "date_trunc('fortnight', created_at) = '2023-01-01'",
+			wantErr: true,
+		},
+		{
+			name:    "function not registered",
+			input:   "md5(name) = 'x'",
+			wantErr: true,
+		},
+		{
+			name:    "field argument not allowed",
+			input:   "lower(password) = 'x'",
+			wantErr: true,
+		},
+		{
+			name:    "wrong number of arguments",
+			input:   "lower(name, email) = 'x'",
+			wantErr: true,
+		},
+		{
+			name:    "wrong argument type",
+			input:   "lower(42) = 'x'",
+			wantErr: true,
+		},
+		{
+			name:    "literal arguments only",
+			input:   "lower('abc') = 'abc'",
+			wantErr: true,
+		},
+		{
+			name:    "literal arguments only in nested call",
+			input:   "length(lower('abc')) > 1",
+			wantErr: true,
+		},
+		{
+			name:    "literal arguments only on the value side",
+			input:   "name = upper('abc')",
+			wantErr: true,
+		},
+		{
+			name:    "return type mismatch",
+			input:   "length(name) = 'three'",
+			wantErr: true,
+		},
+		{
+			name:    "return type mismatch in IN",
+			input:   "length(name) IN (1, 'two')",
+			wantErr: true,
+		},
+		{
+			name:    "LIKE on non-string function",
+			input:   "length(name) LIKE '1%'",
+			wantErr: true,
+		},
+		{
+			name:    "missing closing parenthesis",
+			input:   "lower(name = 'x'",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewFilterParser([]string{"name", "email", "created_at"})
+			for _, fn := range []Function{FunctionLower, FunctionUpper, FunctionLength, FunctionDateTrunc} {
+				if err := p.RegisterFunction(fn); err != nil {
+					t.Fatalf("RegisterFunction() error = %v", err)
+				}
+			}
+
+			node, err := p.Parse(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if err == nil && tt.checkNode != nil {
+				tt.checkNode(t, node)
+			}
+		})
+	}
+}
+
+func TestFilterParser_LeadingWildcards(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		allowed bool
+		wantErr bool
+	}{
+		{"allowed by default", "name LIKE '%john'", true, false},
+		{"leading percent", "name LIKE '%john'", false, true},
+		{"leading underscore", "name ILIKE '_ohn'", false, true},
+		{"NOT LIKE leading percent", "name NOT LIKE '%john'", false, true},
+		{"trailing wildcard", "name LIKE 'john%'", false, false},
+		{"escaped leading wildcard", "name LIKE '!%john' ESCAPE '!'", false, false},
+		{"CONTAINS allowed by default", "name CONTAINS 'john'", true, false},
+		{"CONTAINS", "name CONTAINS 'john'", false, true},
+		{"NOT CONTAINS", "name NOT CONTAINS 'john'", false, true},
+		{"ENDS WITH", "name ENDS WITH 'john'", false, true},
+		{"STARTS WITH", "name STARTS WITH 'john'", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewFilterParser([]string{"name"}, WithLeadingWildcards(tt.allowed))
+			if _, err := p.Parse(tt.input); (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFilterParser_QuotedIdentifiers(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr string
+	}{
+		{
+			name:  "field with hyphen",
+			input: `"first-name" = 'John'`,
+			want:  "(first-name = 'John')",
+		},
+		{
+			name:  "keyword fields",
+			input: "`order` > 3 AND \"from\" = 'x' AND \"is\" IS NULL",
+			want:  "(((order > 3) AND (from = 'x')) AND is IS NULL)",
+		},
+		{
+			name:  "quoted field as value",
+			input: `"first-name" = "last-name"`,
+			want:  "(first-name = last-name)",
+		},
+		{
+			name:  "quoted dotted path",
+			input: `"address.city" IN ('Berlin', 'Paris')`,
+			want:  "address.city IN ('Berlin', 'Paris')",
+		},
+		{
+			name:  "plain identifier still allowed",
+			input: "name = 'John'",
+			want:  "(name = 'John')",
+		},
+		{
+			name:    "unquoted keyword field",
+			input:   "order > 3 AND is = 1",
+			wantErr: "parsing errors",
+		},
+		{
+			name:    "quoted field not allowed",
+			input:   `"last name" = 'Doe'`,
+			wantErr: "error on field 'last name': field not allowed",
+		},
+		{
+			name:    "quoted identifier is not a function",
+			input:   `"lower"(name) = 'x'`,
+			wantErr: "parsing errors",
+		},
+		{
+			name:    "unterminated quoted identifier",
+			input:   `"first-name = 'John'`,
+			wantErr: "illegal token",
+		},
+	}
+
+	allowed := []string{"first-name", "last-name", "order", "from", "is", "name", "address.city", "lower"}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := NewFilterParser(allowed)
+			node, err := parser.Parse(tt.input)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Parse() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			if node.String() != tt.want {
+				t.Errorf("Parse() = %s, want %s", node, tt.want)
+			}
+		})
+	}
+}
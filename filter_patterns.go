@@ -0,0 +1,103 @@
+package qfv
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+)
+
+// patternLimits holds the caps applied to regex and SIMILAR TO patterns, zero means no limit
+type patternLimits struct {
+	maxLength     int
+	maxComplexity int
+}
+
+// WithMaxPatternLength limits the length, in characters, of regex and SIMILAR TO patterns
+func WithMaxPatternLength(n int) FilterOption {
+	return filterOption(func(p *FilterParser) {
+		p.patternLimits.maxLength = n
+	})
+}
+
+// WithMaxPatternComplexity limits the complexity of regex and SIMILAR TO patterns,
+// measured as the number of instructions of the compiled regular expression.
+// Nested repetitions such as (a{1,20}){1,20} grow quickly past reasonable limits.
+func WithMaxPatternComplexity(n int) FilterOption {
+	return filterOption(func(p *FilterParser) {
+		p.patternLimits.maxComplexity = n
+	})
+}
+
+// compile checks the pattern against the limits and compiles expr,
+// the Go regular expression the pattern translates to
+func (l patternLimits) compile(pattern, expr string) (*regexp.Regexp, error) {
+	if l.maxLength > 0 && len([]rune(pattern)) > l.maxLength {
+		return nil, fmt.Errorf("pattern exceeds maximum length of %d characters", l.maxLength)
+	}
+
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	if l.maxComplexity > 0 {
+		prog, err := syntax.Compile(re.Simplify())
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+
+		if len(prog.Inst) > l.maxComplexity {
+			return nil, fmt.Errorf("pattern exceeds maximum complexity of %d", l.maxComplexity)
+		}
+	}
+
+	return regexp.Compile(expr)
+}
+
+// SimilarToRegexp translates a SQL SIMILAR TO pattern into an equivalent
+// anchored Go regular expression (e.g. 'J%(n|hn)' becomes ^(?:J.*(n|hn))$)
+func SimilarToRegexp(pattern string) (string, error) {
+	var sb strings.Builder
+	sb.WriteString("^(?:")
+
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; r {
+		case '%':
+			sb.WriteString(".*")
+		case '_':
+			sb.WriteString(".")
+		case '|', '*', '+', '?', '(', ')', '{', '}', ',':
+			sb.WriteRune(r)
+		case '[':
+			// Bracket expressions have the same meaning in both syntaxes
+			end := i + 1
+			if end < len(runes) && runes[end] == '^' {
+				end++
+			}
+			if end < len(runes) && runes[end] == ']' {
+				end++ // A leading ] is part of the set
+			}
+			for end < len(runes) && runes[end] != ']' {
+				end++
+			}
+			if end == len(runes) {
+				return "", fmt.Errorf("missing closing ] in pattern")
+			}
+			sb.WriteString(string(runes[i : end+1]))
+			i = end
+		case '\\':
+			if i+1 == len(runes) {
+				return "", fmt.Errorf("pattern ends with escape character")
+			}
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(runes[i])))
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+
+	sb.WriteString(")$")
+	return sb.String(), nil
+}
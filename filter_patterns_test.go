@@ -0,0 +1,129 @@
+package qfv
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSimilarToRegexp(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		want    string
+		wantErr bool
+	}{
+		{"wildcards", "J%n_", `^(?:J.*n.)$`, false},
+		{"alternation and grouping", "(Jo|Ja)%", `^(?:(Jo|Ja).*)$`, false},
+		{"repetition", "a{2,3}b+c*d?", `^(?:a{2,3}b+c*d?)$`, false},
+		{"literal regex metacharacters", "a.b^c$", `^(?:a\.b\^c\$)$`, false},
+		{"escaped wildcard", `50\%`, `^(?:50%)$`, false},
+		{"bracket expression", "[a-c_%]x", `^(?:[a-c_%]x)$`, false},
+		{"bracket expression with leading ]", "[]a]", `^(?:[]a])$`, false},
+		{"unterminated bracket expression", "[abc", "", true},
+		{"trailing escape", `abc\`, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SimilarToRegexp(tt.pattern)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SimilarToRegexp() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("SimilarToRegexp() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterParser_Patterns(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		opts    []FilterOption
+		wantErr string
+	}{
+		{
+			name:  "valid regex",
+			input: "name ~ '^[a-z]+$'",
+		},
+		{
+			name:  "valid case-insensitive regex",
+			input: "name !~* '^admin'",
+		},
+		{
+			name:    "invalid regex",
+			input:   "name ~ '^(abc'",
+			wantErr: "error on field 'name' at 1:8: invalid pattern",
+		},
+		{
+			name:    "invalid regex repetition",
+			input:   "name ~* 'a**'",
+			wantErr: "error on field 'name' at 1:9: invalid pattern",
+		},
+		{
+			name:  "valid SIMILAR TO",
+			input: "name SIMILAR TO '(Jo|Ja)%'",
+		},
+		{
+			name:    "invalid SIMILAR TO",
+			input:   "name NOT SIMILAR TO '(Jo|Ja%'",
+			wantErr: "error on field 'name' at 1:21: invalid pattern",
+		},
+		{
+			name:    "SIMILAR TO with integer pattern",
+			input:   "name SIMILAR TO 42",
+			wantErr: "expected string pattern for SIMILAR TO operator",
+		},
+		{
+			name:    "pattern too long",
+			input:   "name ~ 'abcdef'",
+			opts:    []FilterOption{WithMaxPatternLength(5)},
+			wantErr: "pattern exceeds maximum length of 5 characters",
+		},
+		{
+			name:  "pattern within length",
+			input: "name ~ 'abcde'",
+			opts:  []FilterOption{WithMaxPatternLength(5)},
+		},
+		{
+			name:    "SIMILAR TO pattern too long",
+			input:   "name SIMILAR TO 'abcdef%'",
+			opts:    []FilterOption{WithMaxPatternLength(5)},
+			wantErr: "pattern exceeds maximum length of 5 characters",
+		},
+		{
+			name:    "pattern too complex",
+			input:   "name ~ '(a{1,20}){1,20}'",
+			opts:    []FilterOption{WithMaxPatternComplexity(100)},
+			wantErr: "pattern exceeds maximum complexity of 100",
+		},
+		{
+			name:  "pattern within complexity",
+			input: "name ~ '^[a-z]+@example\\.com$'",
+			opts:  []FilterOption{WithMaxPatternComplexity(100)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewFilterParser([]string{"name"}, tt.opts...)
+			_, err := p.Parse(tt.input)
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("expected error containing '%s', got nil", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing '%s', got '%v'", tt.wantErr, err)
+			}
+		})
+	}
+}
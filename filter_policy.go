@@ -0,0 +1,235 @@
+package qfv
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// RequiredPredicate is a condition on a field that every filter must enforce
+type RequiredPredicate struct {
+	Field     string
+	Operators []TokenType // Accepted operators (e.g. TokenOperatorEqual), empty accepts = and IN
+}
+
+// defaultRequiredOperators are the operators accepted by a RequiredPredicate without Operators,
+// the ones restricting the field to known values
+var defaultRequiredOperators = []TokenType{TokenOperatorEqual, TokenOperatorIn}
+
+// FilterPolicy describes the fields a filter must and must not reference.
+// It is checked over the parsed AST with Check, e.g. to guarantee that filters
+// on multi-tenant endpoints always restrict tenant_id. Field names are canonical,
+// see FilterParser.ResolvePolicy for policies written with aliases.
+type FilterPolicy struct {
+	// Required predicates must appear in a conjunctive position on every path of the
+	// filter: on one side of each AND, on both sides of each OR, and never under NOT.
+	// Predicates comparing the field with another field do not count.
+	Required []RequiredPredicate
+
+	// Forbidden fields must not be referenced anywhere in the filter
+	Forbidden []string
+}
+
+// ResolvePolicy returns the policy with the canonical names of its fields, so
+// policies may use aliases, and an error if a field is unknown to the parser
+func (p *FilterParser) ResolvePolicy(policy FilterPolicy) (FilterPolicy, error) {
+	resolved := FilterPolicy{
+		Required:  slices.Clone(policy.Required),
+		Forbidden: slices.Clone(policy.Forbidden),
+	}
+
+	for i, required := range resolved.Required {
+		canonical, ok := p.fields.known(required.Field)
+		if !ok {
+			return FilterPolicy{}, fmt.Errorf("policy: unknown required field %s", required.Field)
+		}
+		resolved.Required[i].Field = canonical
+	}
+
+	for i, field := range resolved.Forbidden {
+		canonical, ok := p.fields.known(field)
+		if !ok {
+			return FilterPolicy{}, fmt.Errorf("policy: unknown forbidden field %s", field)
+		}
+		resolved.Forbidden[i] = canonical
+	}
+
+	return resolved, nil
+}
+
+// Check verifies that the filter satisfies the policy, node may be nil for an empty filter
+func (p FilterPolicy) Check(node Node) error {
+	forbidden := make(map[string]any, len(p.Forbidden))
+	for _, f := range p.Forbidden {
+		forbidden[f] = struct{}{}
+	}
+
+	var err error
+	Inspect(node, func(n Node) bool {
+		if id, ok := n.(*IdentifierNode); ok && err == nil {
+			if _, ok := forbidden[id.Name]; ok {
+				err = &QFVFilterError{Field: id.Original(), Message: "field is forbidden by policy", Pos: id.Pos()}
+			}
+		}
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, required := range p.Required {
+		if node == nil || !required.enforcedBy(node) {
+			return &QFVFilterError{Field: required.Field, Message: required.violation()}
+		}
+	}
+
+	return nil
+}
+
+// enforcedBy reports whether the predicate is enforced on every path of the filter
+func (r RequiredPredicate) enforcedBy(node Node) bool {
+	switch n := node.(type) {
+	case *GroupNode:
+		return r.enforcedBy(n.Expression)
+	case *UnaryOperatorNode:
+		return false // A negated predicate does not restrict the field
+	case *BinaryOperatorNode:
+		switch n.Operator {
+		case TokenOperatorAnd:
+			return r.enforcedBy(n.Left) || r.enforcedBy(n.Right)
+		case TokenOperatorOr:
+			return r.enforcedBy(n.Left) && r.enforcedBy(n.Right)
+		}
+	}
+
+	field, op, values, ok := predicateOf(node)
+	if !ok || field != r.Field || !r.accepts(op) {
+		return false
+	}
+
+	for _, v := range values {
+		if len(referencedFields(v)) > 0 {
+			return false // e.g. tenant_id = tenant_id
+		}
+	}
+
+	return true
+}
+
+// operators returns the accepted operators
+func (r RequiredPredicate) operators() []TokenType {
+	if len(r.Operators) == 0 {
+		return defaultRequiredOperators
+	}
+
+	return r.Operators
+}
+
+// accepts reports whether the operator is one of the accepted operators
+func (r RequiredPredicate) accepts(op TokenType) bool {
+	return slices.Contains(r.operators(), op)
+}
+
+// violation describes the requirement for error messages
+func (r RequiredPredicate) violation() string {
+	ops := make([]string, len(r.operators()))
+	for i, op := range r.operators() {
+		ops[i] = op.String()
+	}
+
+	return fmt.Sprintf("filter must restrict the field with %s on every condition path", strings.Join(ops, " or "))
+}
+
+// predicateOf returns the field name, the operator and the values of a predicate
+// on a bare field (e.g. tenant_id = 1), ok is false for any other node
+func predicateOf(node Node) (field string, op TokenType, values []Node, ok bool) {
+	var fieldNode Node
+	switch n := node.(type) {
+	case *BinaryOperatorNode:
+		if n.Operator == TokenOperatorAnd || n.Operator == TokenOperatorOr {
+			return "", "", nil, false
+		}
+		fieldNode, op, values = n.Left, n.Operator, []Node{n.Right}
+	case *CustomOperatorNode:
+		fieldNode, op, values = n.Left, TokenType(strings.ToUpper(n.Operator.Symbol)), []Node{n.Right}
+	case *InNode:
+		fieldNode, op, values = n.Field, TokenOperatorIn, n.Values
+		if n.IsNot {
+			op = TokenOperatorNotIn
+		}
+	case *BetweenNode:
+		fieldNode, op, values = n.Field, TokenOperatorBetween, []Node{n.Lower, n.Upper}
+		if n.IsNot {
+			op = TokenOperatorNotBetween
+		}
+	case *IsNullNode:
+		fieldNode, op = n.Field, TokenOperatorIsNull
+		if n.IsNot {
+			op = TokenOperatorIsNotNull
+		}
+	case *LikeNode:
+		fieldNode, op, values = n.Field, TokenOperatorLike, []Node{n.Pattern}
+		if n.IsCaseInsensitive {
+			op = TokenOperatorILike
+		}
+	case *StringMatchNode:
+		fieldNode, op, values = n.Field, n.Operator, []Node{n.Value}
+	case *SimilarToNode:
+		fieldNode, op, values = n.Field, TokenOperatorSimilarTo, []Node{n.Pattern}
+	case *RegexMatchNode:
+		fieldNode, values = n.Field, []Node{n.Pattern}
+		switch {
+		case n.IsNot && n.IsCaseInsensitive:
+			op = TokenOperatorNotRegexMatchCI
+		case n.IsNot:
+			op = TokenOperatorNotRegexMatchCS
+		case n.IsCaseInsensitive:
+			op = TokenOperatorRegexMatchCI
+		default:
+			op = TokenOperatorRegexMatchCS
+		}
+	case *DistinctNode:
+		fieldNode, op = n.Field, TokenOperatorDistinct
+	default:
+		return "", "", nil, false
+	}
+
+	id, ok := fieldNode.(*IdentifierNode)
+	if !ok {
+		return "", "", nil, false
+	}
+
+	return id.Name, op, values, true
+}
+
+// RestrictFilter combines the user filter with mandatory server-side filters
+// (e.g. tenant_id = 7) into (<user>) AND (<mandatory>) ... Each operand is wrapped
+// in a GroupNode, so an OR in the user filter can never escape the conjunction
+// whatever translator renders the result. The nodes are not modified.
+// Nil filters are skipped, and the result is nil when every filter is nil.
+func RestrictFilter(user Node, mandatory ...Node) Node {
+	var result Node
+	for _, node := range append([]Node{user}, mandatory...) {
+		if node == nil {
+			continue
+		}
+
+		if _, ok := node.(*GroupNode); !ok {
+			node = &GroupNode{baseNode: baseNode{pos: node.Pos()}, Expression: node}
+		}
+
+		if result == nil {
+			result = node
+			continue
+		}
+
+		result = &BinaryOperatorNode{
+			baseNode: baseNode{pos: result.Pos()},
+			Left:     result,
+			Right:    node,
+			Operator: TokenOperatorAnd,
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,318 @@
+package qfv
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFilterPolicy_Check(t *testing.T) {
+	tenant := FilterPolicy{
+		Required: []RequiredPredicate{
+			{Field: "tenant_id", Operators: []TokenType{TokenOperatorEqual, TokenOperatorIn}},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		policy  FilterPolicy
+		wantErr string
+	}{
+		{
+			name:   "required predicate alone",
+			input:  "tenant_id = 1",
+			policy: tenant,
+		},
+		{
+			name:   "required predicate in AND",
+			input:  "name = 'John' AND tenant_id IN (1, 2)",
+			policy: tenant,
+		},
+		{
+			name:   "required predicate in nested AND",
+			input:  "(name = 'John' OR age > 3) AND (active = true AND tenant_id = 1)",
+			policy: tenant,
+		},
+		{
+			name:   "required predicate on both sides of OR",
+			input:  "(tenant_id = 1 AND name = 'John') OR (tenant_id = 1 AND age > 3)",
+			policy: tenant,
+		},
+		{
+			name:    "empty filter",
+			input:   "",
+			policy:  tenant,
+			wantErr: "error on field 'tenant_id': filter must restrict the field with = or IN on every condition path",
+		},
+		{
+			name:    "required predicate missing",
+			input:   "name = 'John'",
+			policy:  tenant,
+			wantErr: "error on field 'tenant_id'",
+		},
+		{
+			name:    "required predicate on one side of OR",
+			input:   "tenant_id = 1 OR name = 'John'",
+			policy:  tenant,
+			wantErr: "error on field 'tenant_id'",
+		},
+		{
+			name:    "OR escapes group",
+			input:   "tenant_id = 1 AND name = 'John' OR age > 3",
+			policy:  tenant,
+			wantErr: "error on field 'tenant_id'",
+		},
+		{
+			name:    "negated required predicate",
+			input:   "NOT tenant_id = 1",
+			policy:  tenant,
+			wantErr: "error on field 'tenant_id'",
+		},
+		{
+			name:    "required field with other operator",
+			input:   "tenant_id > 0",
+			policy:  tenant,
+			wantErr: "error on field 'tenant_id'",
+		},
+		{
+			name:    "required field compared with a field",
+			input:   "tenant_id = tenant_id",
+			policy:  tenant,
+			wantErr: "error on field 'tenant_id'",
+		},
+		{
+			name:    "required field inside a function",
+			input:   "lower(tenant_id) = '1'",
+			policy:  tenant,
+			wantErr: "error on field 'tenant_id'",
+		},
+		{
+			name:   "equality accepted by default",
+			input:  "tenant_id = 1",
+			policy: FilterPolicy{Required: []RequiredPredicate{{Field: "tenant_id"}}},
+		},
+		{
+			name:   "IN accepted by default",
+			input:  "tenant_id IN (1, 2)",
+			policy: FilterPolicy{Required: []RequiredPredicate{{Field: "tenant_id"}}},
+		},
+		{
+			name:    "inequality rejected by default",
+			input:   "tenant_id <> 1",
+			policy:  FilterPolicy{Required: []RequiredPredicate{{Field: "tenant_id"}}},
+			wantErr: "error on field 'tenant_id': filter must restrict the field with = or IN on every condition path",
+		},
+		{
+			name:    "IS NOT NULL rejected by default",
+			input:   "tenant_id IS NOT NULL",
+			policy:  FilterPolicy{Required: []RequiredPredicate{{Field: "tenant_id"}}},
+			wantErr: "error on field 'tenant_id'",
+		},
+		{
+			name:    "DISTINCT FROM rejected by default",
+			input:   "tenant_id DISTINCT FROM 1",
+			policy:  FilterPolicy{Required: []RequiredPredicate{{Field: "tenant_id"}}},
+			wantErr: "error on field 'tenant_id'",
+		},
+		{
+			name:   "other operators accepted when listed",
+			input:  "tenant_id IS NOT NULL",
+			policy: FilterPolicy{Required: []RequiredPredicate{{Field: "tenant_id", Operators: []TokenType{TokenOperatorIsNotNull}}}},
+		},
+		{
+			name:   "forbidden field not referenced",
+			input:  "name = 'John'",
+			policy: FilterPolicy{Forbidden: []string{"ssn"}},
+		},
+		{
+			name:    "forbidden field referenced",
+			input:   "name = 'John' OR ssn LIKE '123%'",
+			policy:  FilterPolicy{Forbidden: []string{"ssn"}},
+			wantErr: "error on field 'ssn' at 1:18: field is forbidden by policy",
+		},
+		{
+			name:    "forbidden field on the value side",
+			input:   "name = ssn",
+			policy:  FilterPolicy{Forbidden: []string{"ssn"}},
+			wantErr: "error on field 'ssn' at 1:8: field is forbidden by policy",
+		},
+		{
+			name:    "forbidden field in a function",
+			input:   "length(ssn) > 3",
+			policy:  FilterPolicy{Forbidden: []string{"ssn"}},
+			wantErr: "error on field 'ssn' at 1:8: field is forbidden by policy",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := NewFilterParser([]string{"tenant_id", "name", "age", "active", "ssn"})
+			if err := parser.RegisterFunction(FunctionLower); err != nil {
+				t.Fatal(err)
+			}
+			if err := parser.RegisterFunction(FunctionLength); err != nil {
+				t.Fatal(err)
+			}
+
+			var node Node
+			if tt.input != "" {
+				var err error
+				node, err = parser.Parse(tt.input)
+				if err != nil {
+					t.Fatalf("Parse() error = %v", err)
+				}
+			}
+
+			err := tt.policy.Check(node)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Check() error = %v, want nil", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Check() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFilterParser_ResolvePolicy(t *testing.T) {
+	registry, err := NewFieldRegistry(
+		FieldDef{Name: "email", Aliases: []string{"mail"}},
+		FieldDef{Name: "tenant_id", Aliases: []string{"tenantId"}},
+	)
+	if err != nil {
+		t.Fatalf("NewFieldRegistry() error = %v", err)
+	}
+	parser := NewFilterParser([]string{"name"}, WithFieldRegistry(registry))
+
+	policy, err := parser.ResolvePolicy(FilterPolicy{
+		Required:  []RequiredPredicate{{Field: "tenantId"}},
+		Forbidden: []string{"mail"},
+	})
+	if err != nil {
+		t.Fatalf("ResolvePolicy() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{name: "canonical names", input: "tenant_id = 1 AND name = 'John'"},
+		{name: "aliases", input: "tenantId = 1 AND name = 'John'"},
+		{name: "forbidden by alias", input: "tenant_id = 1 AND mail = 'a'", wantErr: "error on field 'mail' at 1:19: field is forbidden by policy"},
+		{name: "forbidden by canonical name", input: "tenant_id = 1 AND email = 'a'", wantErr: "error on field 'email' at 1:19: field is forbidden by policy"},
+		{name: "required by alias", input: "name = 'John'", wantErr: "error on field 'tenant_id'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, err := parser.Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			err = policy.Check(node)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Check() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Check() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	want := "policy: unknown forbidden field ssn"
+	if _, err := parser.ResolvePolicy(FilterPolicy{Forbidden: []string{"ssn"}}); err == nil || err.Error() != want {
+		t.Errorf("ResolvePolicy() error = %v, want %s", err, want)
+	}
+}
+
+func TestRestrictFilter(t *testing.T) {
+	userParser := NewFilterParser([]string{"name", "age"})
+	serverParser := NewFilterParser([]string{"tenant_id", "deleted_at"})
+
+	parse := func(p *FilterParser, input string) Node {
+		t.Helper()
+		node, err := p.Parse(input)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", input, err)
+		}
+		return node
+	}
+
+	tests := []struct {
+		name      string
+		user      Node
+		mandatory []Node
+		want      string
+	}{
+		{
+			name:      "user OR cannot escape",
+			user:      parse(userParser, "name = 'John' OR age > 30"),
+			mandatory: []Node{parse(serverParser, "tenant_id = 7")},
+			want:      "((((name = 'John') OR (age > 30))) AND ((tenant_id = 7)))",
+		},
+		{
+			name:      "grouped user filter is not wrapped twice",
+			user:      parse(userParser, "(name = 'John')"),
+			mandatory: []Node{parse(serverParser, "tenant_id = 7")},
+			want:      "(((name = 'John')) AND ((tenant_id = 7)))",
+		},
+		{
+			name: "several mandatory filters",
+			user: parse(userParser, "age > 30"),
+			mandatory: []Node{
+				parse(serverParser, "tenant_id = 7"),
+				parse(serverParser, "deleted_at IS NULL"),
+			},
+			want: "((((age > 30)) AND ((tenant_id = 7))) AND (deleted_at IS NULL))",
+		},
+		{
+			name:      "empty user filter",
+			user:      nil,
+			mandatory: []Node{parse(serverParser, "tenant_id = 7")},
+			want:      "((tenant_id = 7))",
+		},
+		{
+			name: "no mandatory filter",
+			user: parse(userParser, "age > 30"),
+			want: "((age > 30))",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userBefore := ""
+			if tt.user != nil {
+				userBefore = tt.user.String()
+			}
+
+			got := RestrictFilter(tt.user, tt.mandatory...)
+			if got.String() != tt.want {
+				t.Errorf("RestrictFilter() = %s, want %s", got, tt.want)
+			}
+
+			if tt.user != nil && tt.user.String() != userBefore {
+				t.Errorf("RestrictFilter() modified the user filter: %s", tt.user)
+			}
+
+			policy := FilterPolicy{Required: []RequiredPredicate{{Field: "tenant_id"}}}
+			if len(tt.mandatory) > 0 {
+				if err := policy.Check(got); err != nil {
+					t.Errorf("Check() error = %v, want nil", err)
+				}
+			}
+		})
+	}
+
+	if got := RestrictFilter(nil); got != nil {
+		t.Errorf("RestrictFilter(nil) = %v, want nil", got)
+	}
+}
@@ -10,6 +10,7 @@ type TokenType string
 
 const (
 	TokenIdentifier       TokenType = "IDENTIFIER"        // Identifier represents a variable name or keyword
+	TokenQuotedIdentifier TokenType = "QUOTED_IDENTIFIER" // QuotedIdentifier represents a field name in double quotes or backticks, never a keyword
 	TokenOperator         TokenType = "OPERATOR"          // Operator represents an operator (e.g., =, <, >, etc.)
 	TokenString           TokenType = "STRING"            // String represents a string literal
 	TokenBoolean          TokenType = "BOOLEAN"           // Boolean represents a boolean literal (true/false)
@@ -48,6 +49,15 @@ const (
 	TokenOperatorNotDistinct  TokenType = "NOT DISTINCT"
 	TokenOperatorSimilarTo    TokenType = "SIMILAR TO"
 	TokenOperatorNotSimilarTo TokenType = "NOT SIMILAR TO"
+	// ---- String Operators ----
+	TokenOperatorILike         TokenType = "ILIKE"           // Case-insensitive LIKE
+	TokenOperatorNotILike      TokenType = "NOT ILIKE"       // Case-insensitive NOT LIKE
+	TokenOperatorContains      TokenType = "CONTAINS"        // Literal substring match
+	TokenOperatorNotContains   TokenType = "NOT CONTAINS"    // Literal substring non-match
+	TokenOperatorStartsWith    TokenType = "STARTS WITH"     // Literal prefix match
+	TokenOperatorNotStartsWith TokenType = "NOT STARTS WITH" // Literal prefix non-match
+	TokenOperatorEndsWith      TokenType = "ENDS WITH"       // Literal suffix match
+	TokenOperatorNotEndsWith   TokenType = "NOT ENDS WITH"   // Literal suffix non-match
 	// ---- Regex Operators ----
 	TokenOperatorRegexMatchCS    TokenType = "~"   // Case-sensitive regex match
 	TokenOperatorNotRegexMatchCS TokenType = "!~"  // Case-sensitive regex non-match
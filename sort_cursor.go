@@ -0,0 +1,154 @@
+package qfv
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QFVCursorError is returned for cursors that can't be encoded or decoded
+type QFVCursorError struct {
+	Message string
+}
+
+func (e *QFVCursorError) Error() string {
+	return fmt.Sprintf("error: %s", e.Message)
+}
+
+// CursorCodec encodes the sort values of the last row of a page into an opaque
+// cursor and decodes them back for KeysetFilter. Cursors are signed with the key,
+// so clients can't forge or alter them, and are tied to the sort they were
+// created for, so they are rejected when the sort changes.
+type CursorCodec struct {
+	key []byte
+}
+
+// cursorPayload is the signed content of a cursor
+type cursorPayload struct {
+	Sort   string `json:"s"` // Fingerprint of the sort
+	Values []any  `json:"v"` // Values of the sort fields, in order
+}
+
+// NewCursorCodec creates a codec signing the cursors with the key, which
+// should be a secret of at least 32 random bytes
+func NewCursorCodec(key []byte) (*CursorCodec, error) {
+	if len(key) == 0 {
+		return nil, fmt.Errorf("cursor key is required")
+	}
+
+	return &CursorCodec{key: bytes.Clone(key)}, nil
+}
+
+// Encode returns the cursor for the row with the given values in the sort order.
+// Values are keyed by field name, like for KeysetFilter.
+func (c *CursorCodec) Encode(sort SortNode, values map[string]any) (string, error) {
+	payload := cursorPayload{Sort: sortFingerprint(sort), Values: make([]any, len(sort.Fields))}
+	for i, f := range sort.Fields {
+		value := rowValue(values, f.Field)
+		if value == nil {
+			return "", &QFVCursorError{Message: fmt.Sprintf("missing value for sort field %s", f.Field)}
+		}
+
+		if t, ok := value.(time.Time); ok {
+			value = t.Format(time.RFC3339Nano)
+		}
+		payload.Values[i] = value
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", &QFVCursorError{Message: fmt.Sprintf("invalid cursor values: %v", err)}
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data) + "." + base64.RawURLEncoding.EncodeToString(c.sign(data)), nil
+}
+
+// Decode returns the values of the sort fields held by the cursor, keyed by field
+// name. It fails if the cursor was altered or was created for another sort.
+// Integers decode as int64, or uint64 beyond the int64 range, other numbers as
+// float64 and times as RFC 3339 strings.
+func (c *CursorCodec) Decode(sort SortNode, cursor string) (map[string]any, error) {
+	invalid := &QFVCursorError{Message: "invalid cursor"}
+
+	encoded, signature, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return nil, invalid
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, invalid
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, c.sign(data)) {
+		return nil, invalid
+	}
+
+	var payload cursorPayload
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&payload); err != nil {
+		return nil, invalid
+	}
+
+	if payload.Sort != sortFingerprint(sort) || len(payload.Values) != len(sort.Fields) {
+		return nil, &QFVCursorError{Message: "cursor does not match the sort"}
+	}
+
+	values := make(map[string]any, len(sort.Fields))
+	for i, f := range sort.Fields {
+		value := payload.Values[i]
+		if n, ok := value.(json.Number); ok {
+			if value, ok = decodeNumber(n); !ok {
+				return nil, invalid
+			}
+		}
+		values[f.Field] = value
+	}
+
+	return values, nil
+}
+
+// decodeNumber converts a JSON number to int64, uint64 or float64,
+// keeping integers exact
+func decodeNumber(n json.Number) (any, bool) {
+	if i, err := n.Int64(); err == nil {
+		return i, true
+	}
+
+	if u, err := strconv.ParseUint(n.String(), 10, 64); err == nil {
+		return u, true
+	}
+
+	f, err := n.Float64()
+	return f, err == nil
+}
+
+// sign returns the signature of the data
+func (c *CursorCodec) sign(data []byte) []byte {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// sortFingerprint returns a short digest identifying the sort
+func sortFingerprint(sort SortNode) string {
+	var b strings.Builder
+	for _, f := range sort.Fields {
+		expression := f.Field
+		if f.Function != nil {
+			expression = f.Function.String()
+		}
+		fmt.Fprintf(&b, "%s:%s:%s,", expression, f.Direction, f.Nulls)
+	}
+
+	sum := sha256.Sum256([]byte(b.String()))
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
@@ -0,0 +1,132 @@
+package qfv
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCursorCodec(t *testing.T) {
+	codec, err := NewCursorCodec([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("NewCursorCodec() error = %v", err)
+	}
+
+	parser := NewSortParser([]string{"name", "score", "id", "created_at"})
+	sort, err := parser.Parse("created_at DESC, name ASC, score ASC, id ASC")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	row := map[string]any{
+		"created_at": time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC),
+		"name":       "John",
+		"score":      9.5,
+		"id":         42,
+		"ignored":    "x",
+	}
+
+	cursor, err := codec.Encode(sort, row)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		got, err := codec.Decode(sort, cursor)
+		if err != nil {
+			t.Fatalf("Decode() error = %v", err)
+		}
+
+		want := map[string]any{"created_at": "2024-05-01T10:30:00Z", "name": "John", "score": 9.5, "id": int64(42)}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Decode() = %v, want %v", got, want)
+		}
+
+		if _, err := KeysetFilter(sort, got); err != nil {
+			t.Errorf("KeysetFilter() error = %v", err)
+		}
+	})
+
+	encoded, signature, _ := strings.Cut(cursor, ".")
+	tampered := base64.RawURLEncoding.EncodeToString([]byte(strings.Replace(decodeBase64(t, encoded), "John", "Jane", 1)))
+	otherCodec, _ := NewCursorCodec([]byte("another key"))
+	otherSort, _ := parser.Parse("created_at ASC, name ASC, score ASC, id ASC")
+
+	tests := []struct {
+		name    string
+		codec   *CursorCodec
+		sort    SortNode
+		cursor  string
+		wantErr string
+	}{
+		{name: "tampered values", codec: codec, sort: sort, cursor: tampered + "." + signature, wantErr: "error: invalid cursor"},
+		{name: "other key", codec: otherCodec, sort: sort, cursor: cursor, wantErr: "error: invalid cursor"},
+		{name: "other sort", codec: codec, sort: otherSort, cursor: cursor, wantErr: "error: cursor does not match the sort"},
+		{name: "missing signature", codec: codec, sort: sort, cursor: encoded, wantErr: "error: invalid cursor"},
+		{name: "garbage", codec: codec, sort: sort, cursor: "%%%.%%%", wantErr: "error: invalid cursor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.codec.Decode(tt.sort, tt.cursor)
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Decode() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("unsigned integers", func(t *testing.T) {
+		idSort, err := parser.Parse("id ASC")
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+
+		for _, id := range []uint64{1<<63 - 1, 1 << 63, 1<<63 + 1, 1<<64 - 1} {
+			cursor, err := codec.Encode(idSort, map[string]any{"id": id})
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+
+			got, err := codec.Decode(idSort, cursor)
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+
+			filter, err := KeysetFilter(idSort, got)
+			if err != nil {
+				t.Fatalf("KeysetFilter() error = %v", err)
+			}
+			if want := fmt.Sprintf("((id > %d))", id); filter.String() != want {
+				t.Errorf("KeysetFilter() = %s, want %s", filter, want)
+			}
+		}
+	})
+
+	t.Run("missing value", func(t *testing.T) {
+		_, err := codec.Encode(sort, map[string]any{"name": "John"})
+		want := "error: missing value for sort field created_at"
+		if err == nil || err.Error() != want {
+			t.Errorf("Encode() error = %v, want %q", err, want)
+		}
+	})
+
+	t.Run("empty key", func(t *testing.T) {
+		if _, err := NewCursorCodec(nil); err == nil {
+			t.Errorf("NewCursorCodec() error = nil, want error")
+		}
+	})
+}
+
+func decodeBase64(t *testing.T, s string) string {
+	t.Helper()
+
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		t.Fatalf("DecodeString() error = %v", err)
+	}
+
+	return string(data)
+}
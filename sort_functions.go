@@ -0,0 +1,146 @@
+package qfv
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// RegisterFunction allows calls to the function around the sort fields
+// (e.g. lower(last_name) ASC), see Function
+func (p *SortParser) RegisterFunction(fn Function) error {
+	return p.functions.register(fn)
+}
+
+// sortCall parses a function call around a sort field (e.g. date_trunc('day', created_at))
+type sortCall struct {
+	parser *SortParser
+	in     *sortInput
+	field  *IdentifierNode // Field referenced by the call
+}
+
+// parseSortCall parses a call to registered functions around exactly one allowed
+// field, tok being the function name, returning the call and the field
+func (p *SortParser) parseSortCall(in *sortInput, tok Token) (*FunctionCallNode, *IdentifierNode, error) {
+	c := &sortCall{parser: p, in: in}
+
+	call, err := c.call(tok)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if c.field == nil {
+		return nil, nil, &QFVSortError{Field: call.Name, Message: "sort expression must reference a field", Pos: tok.Pos}
+	}
+
+	return call, c.field, nil
+}
+
+// call parses a function call, tok being the function name followed by (
+func (c *sortCall) call(tok Token) (*FunctionCallNode, error) {
+	fn, ok := c.parser.functions[strings.ToLower(tok.Value)]
+	if !ok {
+		return nil, &QFVSortError{Field: tok.Value, Message: "function not allowed", Pos: tok.Pos}
+	}
+	c.in.lexer.Next() // Consume (
+
+	var args []Node
+	if c.in.lexer.Peek().Type == TokenRPAREN {
+		c.in.lexer.Next()
+	} else {
+		for {
+			arg, err := c.arg(c.in.lexer.Next())
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+
+			next := c.in.lexer.Next()
+			if next.Type == TokenRPAREN {
+				break
+			}
+			if next.Type != TokenComma {
+				return nil, &QFVSortError{Field: fn.Name, Message: "expected closing parenthesis after function arguments", Pos: next.Pos}
+			}
+		}
+	}
+
+	if problems := fn.check(args); len(problems) > 0 {
+		return nil, &QFVSortError{Field: fn.Name, Message: problems[0], Pos: tok.Pos}
+	}
+
+	return &FunctionCallNode{baseNode: baseNode{pos: tok.Pos}, Name: fn.Name, Args: args, Function: fn}, nil
+}
+
+// arg parses a function argument: a field, a literal or a nested call
+func (c *sortCall) arg(tok Token) (Node, error) {
+	if tok.Type == TokenIdentifier && c.in.lexer.Peek().Type == TokenLPAREN {
+		return c.call(tok)
+	}
+
+	switch tok.Type {
+	case TokenString, TokenInt, TokenFloat, TokenBoolean:
+		literal, err := newTokenLiteral(tok)
+		if err != nil {
+			return nil, &QFVSortError{Field: tok.Value, Message: err.Error(), Pos: tok.Pos}
+		}
+		return literal, nil
+	case TokenIllegal:
+		return nil, &QFVSortError{Field: tok.Value, Message: illegalTokenMessage(tok), Pos: tok.Pos}
+	}
+
+	name, ok := fieldToken(tok)
+	if !ok {
+		return nil, &QFVSortError{Field: tok.Value, Message: fmt.Sprintf("unexpected token: %s", tok.Type), Pos: tok.Pos}
+	}
+
+	if c.field != nil {
+		return nil, &QFVSortError{Field: name, Message: "sort expression must reference a single field", Pos: tok.Pos}
+	}
+
+	canonical, ok := c.parser.fields.resolve(name, c.in.roles)
+	if !ok {
+		return nil, &QFVSortError{Field: name, Message: "field not allowed for sorting", Pos: tok.Pos}
+	}
+
+	c.field = &IdentifierNode{baseNode: baseNode{pos: tok.Pos}, Name: canonical}
+	if canonical != name {
+		c.field.Alias = name
+	}
+	if path := ParseFieldPath(canonical); path.IsNested() {
+		c.field.Path = path
+	}
+
+	return c.field, nil
+}
+
+// sqlExpression renders a sort expression argument for the dialect
+func sqlExpression(d Dialect, node Node) (string, error) {
+	switch n := node.(type) {
+	case *IdentifierNode:
+		return quoteIdentifier(d, n.Name)
+	case *LiteralNode:
+		if n.Kind == reflect.String {
+			return quoteString(d, stringValue(n)), nil // Escaped again for the dialect
+		}
+		return n.String(), nil // Numbers and booleans are validated by the lexer
+	case *FunctionCallNode:
+		args := make([]string, len(n.Args))
+		for i, arg := range n.Args {
+			sql, err := sqlExpression(d, arg)
+			if err != nil {
+				return "", err
+			}
+			args[i] = sql
+		}
+		return n.Function.Render(d, args...)
+	default:
+		return "", fmt.Errorf("unsupported sort expression %s", node.Type())
+	}
+}
+
+// stringValue returns the value of the string literal, its Text keeping the
+// quotes of the input and the doubled quotes
+func stringValue(n *LiteralNode) string {
+	return strings.ReplaceAll(strings.TrimSuffix(strings.TrimPrefix(n.Text, "'"), "'"), "''", "'")
+}
@@ -0,0 +1,250 @@
+package qfv
+
+import (
+	"testing"
+)
+
+func newFunctionSortParser(t *testing.T, opts ...SortOption) *SortParser {
+	t.Helper()
+
+	p := NewSortParser([]string{"last_name", "title", "created_at", "id", "first-name"}, opts...)
+	for _, fn := range []Function{FunctionLower, FunctionLength, FunctionDateTrunc} {
+		if err := p.RegisterFunction(fn); err != nil {
+			t.Fatalf("RegisterFunction() error = %v", err)
+		}
+	}
+
+	return p
+}
+
+func TestSortParser_Functions(t *testing.T) {
+	p := newFunctionSortParser(t)
+
+	tests := []struct {
+		name    string
+		input   string
+		want    []string // Function call of each sort field, empty for plain fields
+		wantSQL string
+		wantErr string
+	}{
+		{
+			name:    "lower",
+			input:   "lower(last_name) ASC",
+			want:    []string{"lower(last_name)"},
+			wantSQL: `LOWER("last_name") ASC`,
+		},
+		{
+			name:    "case insensitive function name",
+			input:   "LENGTH(title) DESC NULLS LAST, id ASC",
+			want:    []string{"length(title)", ""},
+			wantSQL: `CHAR_LENGTH("title") DESC NULLS LAST, "id" ASC`,
+		},
+		{
+			name:    "literal argument",
+			input:   "date_trunc('day', created_at) DESC, lower(last_name) ASC",
+			want:    []string{"date_trunc('day', created_at)", "lower(last_name)"},
+			wantSQL: `DATE_TRUNC('day', "created_at") DESC, LOWER("last_name") ASC`,
+		},
+		{
+			name:    "quoted field",
+			input:   `lower("first-name") ASC`,
+			want:    []string{"lower(first-name)"},
+			wantSQL: `LOWER("first-name") ASC`,
+		},
+		{
+			name:    "function and field",
+			input:   "lower(last_name) ASC, last_name ASC",
+			want:    []string{"lower(last_name)", ""},
+			wantSQL: `LOWER("last_name") ASC, "last_name" ASC`,
+		},
+		{
+			name:    "function not allowed",
+			input:   "upper(last_name) ASC",
+			wantErr: "error on field 'upper' at 1:1: function not allowed",
+		},
+		{
+			name:    "field not allowed",
+			input:   "lower(password) ASC",
+			wantErr: "error on field 'password' at 1:7: field not allowed for sorting",
+		},
+		{
+			name:    "wrong argument type",
+			input:   "length(42) ASC",
+			wantErr: "error on field 'length' at 1:1: argument 1 must be STRING, got NUMBER",
+		},
+		{
+			name:    "wrong number of arguments",
+			input:   "lower() ASC",
+			wantErr: "error on field 'lower' at 1:1: expected 1 arguments, got 0",
+		},
+		{
+			name:    "two fields",
+			input:   "lower(last_name, title) ASC",
+			wantErr: "error on field 'title' at 1:18: sort expression must reference a single field",
+		},
+		{
+			name:    "validation",
+			input:   "date_trunc('fortnight', created_at) ASC",
+			wantErr: "error on field 'date_trunc' at 1:1: unknown unit 'fortnight'",
+		},
+		{
+			name:    "no field",
+			input:   "lower('x') ASC",
+			wantErr: "error on field 'lower' at 1:1: sort expression must reference a field",
+		},
+		{
+			name:    "duplicate call",
+			input:   "lower(last_name) ASC, LOWER(last_name) DESC",
+			wantErr: "error on field 'last_name' at 1:23: duplicate sort field",
+		},
+		{
+			name:    "unbalanced parenthesis",
+			input:   "lower(last_name ASC",
+			wantErr: "error on field 'lower' at 1:17: expected closing parenthesis after function arguments",
+		},
+		{
+			name:    "trailing tokens",
+			input:   "lower(last_name) x ASC",
+			wantErr: "error on field 'last_name' at 1:18: invalid sort direction",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.Parse(tt.input)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("Parse() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			if len(got.Fields) != len(tt.want) {
+				t.Fatalf("Parse() = %v, want %d fields", got, len(tt.want))
+			}
+			for i, f := range got.Fields {
+				var call string
+				if f.Function != nil {
+					call = f.Function.String()
+				}
+				if call != tt.want[i] {
+					t.Errorf("Fields[%d].Function = %s, want %s", i, call, tt.want[i])
+				}
+			}
+
+			sql, err := got.SQL(DialectPostgres)
+			if err != nil {
+				t.Fatalf("SQL() error = %v", err)
+			}
+			if sql != tt.wantSQL {
+				t.Errorf("SQL() = %s, want %s", sql, tt.wantSQL)
+			}
+		})
+	}
+}
+
+func TestSortParser_FunctionRules(t *testing.T) {
+	p := newFunctionSortParser(t,
+		WithFieldDirections("title", SortAsc),
+		WithTiebreakers("id"),
+		WithUniqueSortFields("last_name"),
+	)
+
+	if _, err := p.Parse("length(title) DESC"); err == nil || err.Error() != "error on field 'title' at 1:1: sort direction DESC not allowed for field" {
+		t.Errorf("Parse() error = %v, want direction not allowed", err)
+	}
+
+	got, err := p.Parse("lower(last_name) DESC")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if len(got.Fields) != 2 || got.Fields[1].Field != "id" || got.Fields[1].Direction != SortDesc {
+		t.Errorf("Parse() = %v, want the id tiebreaker", got)
+	}
+
+	if _, err := KeysetFilter(got, map[string]any{"last_name": "x", "id": 1}); err == nil {
+		t.Errorf("KeysetFilter() error = nil, want unsupported function")
+	}
+
+	mysql, err := got.SQL(DialectMySQL)
+	if err != nil {
+		t.Fatalf("SQL() error = %v", err)
+	}
+	if want := "LOWER(`last_name`) DESC, `id` DESC"; mysql != want {
+		t.Errorf("SQL() = %s, want %s", mysql, want)
+	}
+
+	node, err := p.Parse("date_trunc('day', created_at) ASC")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if _, err := node.SQL(DialectSQLite); err == nil {
+		t.Errorf("SQL() error = nil, want unsupported dialect")
+	}
+}
+
+func TestSortParser_FunctionLiterals(t *testing.T) {
+	p := NewSortParser([]string{"name"})
+	coalesce := Function{
+		Name:    "coalesce3",
+		Args:    []ValueType{ValueTypeString, ValueTypeString, ValueTypeString},
+		Returns: ValueTypeString,
+		Templates: map[Dialect]string{
+			DialectPostgres: "COALESCE(%[1]s, %[2]s, %[3]s)",
+			DialectMySQL:    "COALESCE(%[1]s, %[2]s, %[3]s)",
+		},
+	}
+	if err := p.RegisterFunction(coalesce); err != nil {
+		t.Fatalf("RegisterFunction() error = %v", err)
+	}
+
+	node, err := p.Parse(`coalesce3(name, 'x\', ') OR SLEEP(5) -- ') ASC`)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	tests := []struct {
+		dialect Dialect
+		want    string
+	}{
+		{DialectPostgres, `COALESCE("name", 'x\', ') OR SLEEP(5) -- ') ASC`},
+		{DialectMySQL, "COALESCE(`name`, 'x\\\\', ') OR SLEEP(5) -- ') ASC"},
+	}
+
+	for _, tt := range tests {
+		got, err := node.SQL(tt.dialect)
+		if err != nil {
+			t.Fatalf("SQL() error = %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("SQL(%s) = %s, want %s", tt.dialect, got, tt.want)
+		}
+	}
+
+	node, err = p.Parse(`coalesce3(name, 'it''s', '') DESC`)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got, want := node.Fields[0].Function.String(), `coalesce3(name, 'it''s', '')`; got != want {
+		t.Errorf("String() = %s, want %s", got, want)
+	}
+	if got, _ := node.SQL(DialectMySQL); got != "COALESCE(`name`, 'it''s', '') DESC" {
+		t.Errorf("SQL() = %s", got)
+	}
+}
+
+func TestSortParser_RegisterFunction(t *testing.T) {
+	p := NewSortParser([]string{"name"})
+
+	if err := p.RegisterFunction(FunctionLower); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := p.RegisterFunction(Function{Name: "LOWER"}); err == nil {
+		t.Errorf("expected error for duplicated function")
+	}
+}
@@ -0,0 +1,183 @@
+package qfv
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// KeysetFilter returns the filter selecting the rows that come after the row with
+// the given values in the sort order, for keyset (cursor) pagination. Values are
+// keyed by field name, like the rows of SortNode.Compare.
+//
+// When every field is sorted in the same direction the filter is a row comparison,
+// (a, b) > (1, 'x'), which databases can answer from a composite index. Mixed
+// directions use the expanded form of ExpandedKeysetFilter. The result is wrapped
+// in a GroupNode, ready to be combined with the user filter (see RestrictFilter).
+// The sort should define a total order (see WithTiebreakers). Null values,
+// function calls and NULLS FIRST/LAST are not supported.
+func KeysetFilter(sort SortNode, values map[string]any) (Node, error) {
+	if err := checkKeyset(sort); err != nil {
+		return nil, err
+	}
+
+	for _, f := range sort.Fields[1:] {
+		if f.Direction != sort.Fields[0].Direction {
+			return ExpandedKeysetFilter(sort, values)
+		}
+	}
+
+	literals, err := keysetLiterals(sort, values)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sort.Fields) == 1 {
+		return &GroupNode{Expression: keysetComparison(sort.Fields[0], literals[0])}, nil
+	}
+
+	fields := &ListNode{Values: make([]Node, len(sort.Fields))}
+	for i, f := range sort.Fields {
+		fields.Values[i] = &IdentifierNode{Name: f.Field, Path: f.Path}
+	}
+
+	return &GroupNode{Expression: &BinaryOperatorNode{
+		Left:     fields,
+		Right:    &ListNode{Values: literals},
+		Operator: keysetOperator(sort.Fields[0].Direction),
+	}}, nil
+}
+
+// ExpandedKeysetFilter is like KeysetFilter, always expanding the row comparison
+// into one condition per field: (a > 1) OR (a = 1 AND b < 'x') ...
+// It supports mixed directions and translators without row values.
+func ExpandedKeysetFilter(sort SortNode, values map[string]any) (Node, error) {
+	if err := checkKeyset(sort); err != nil {
+		return nil, err
+	}
+
+	literals, err := keysetLiterals(sort, values)
+	if err != nil {
+		return nil, err
+	}
+
+	var filter Node
+	for i, f := range sort.Fields {
+		var term Node
+		for j := range i {
+			term = and(term, &BinaryOperatorNode{
+				Left:     &IdentifierNode{Name: sort.Fields[j].Field, Path: sort.Fields[j].Path},
+				Right:    literals[j],
+				Operator: TokenOperatorEqual,
+			})
+		}
+		term = and(term, keysetComparison(f, literals[i]))
+
+		if filter == nil {
+			filter = &GroupNode{Expression: term}
+			continue
+		}
+
+		filter = &BinaryOperatorNode{Left: filter, Right: &GroupNode{Expression: term}, Operator: TokenOperatorOr}
+	}
+
+	return &GroupNode{Expression: filter}, nil
+}
+
+// and combines the nodes with AND, left may be nil
+func and(left, right Node) Node {
+	if left == nil {
+		return right
+	}
+
+	return &BinaryOperatorNode{Left: left, Right: right, Operator: TokenOperatorAnd}
+}
+
+// checkKeyset checks that the sort can be used for keyset pagination
+func checkKeyset(sort SortNode) error {
+	if len(sort.Fields) == 0 {
+		return fmt.Errorf("keyset pagination requires a sort")
+	}
+
+	for _, f := range sort.Fields {
+		if f.Function != nil {
+			return fmt.Errorf("keyset pagination does not support function %s on field %s", f.Function.Name, f.Field)
+		}
+		if f.Nulls != NullsDefault {
+			return fmt.Errorf("keyset pagination does not support NULLS %s on field %s", f.Nulls, f.Field)
+		}
+	}
+
+	return nil
+}
+
+// keysetComparison returns the comparison selecting the values after value in the field order
+func keysetComparison(f SortFieldNode, value Node) Node {
+	return &BinaryOperatorNode{
+		Left:     &IdentifierNode{Name: f.Field, Path: f.Path},
+		Right:    value,
+		Operator: keysetOperator(f.Direction),
+	}
+}
+
+// keysetOperator returns the operator selecting the values after a value in the direction
+func keysetOperator(direction SortDirection) TokenType {
+	if direction == SortDesc {
+		return TokenOperatorLessThan
+	}
+
+	return TokenOperatorGreaterThan
+}
+
+// keysetLiterals returns the values of the sort fields as literals, in order
+func keysetLiterals(sort SortNode, values map[string]any) ([]Node, error) {
+	literals := make([]Node, len(sort.Fields))
+	for i, f := range sort.Fields {
+		value := rowValue(values, f.Field)
+		if value == nil {
+			return nil, fmt.Errorf("missing value for sort field %s", f.Field)
+		}
+
+		literal, err := NewLiteral(value)
+		if err != nil {
+			return nil, fmt.Errorf("sort field %s: %w", f.Field, err)
+		}
+		literals[i] = literal
+	}
+
+	return literals, nil
+}
+
+// NewLiteral returns the literal node for a Go value: a string, a boolean,
+// an integer, a floating-point number or a time.Time (as an RFC 3339 string).
+// Unsigned integers beyond the int64 range are kept exact as uint64 values.
+func NewLiteral(value any) (*LiteralNode, error) {
+	switch v := value.(type) {
+	case string:
+		return &LiteralNode{Value: v, Kind: reflect.String, Text: "'" + strings.ReplaceAll(v, "'", "''") + "'"}, nil
+	case time.Time:
+		return NewLiteral(v.Format(time.RFC3339Nano))
+	case bool:
+		return &LiteralNode{Value: v, Kind: reflect.Bool, Text: strconv.FormatBool(v)}, nil
+	case float32:
+		return NewLiteral(float64(v))
+	case float64:
+		return &LiteralNode{Value: v, Kind: reflect.Float64, Text: strconv.FormatFloat(v, 'g', -1, 64)}, nil
+	}
+
+	if i, ok := toInt(value); ok {
+		return &LiteralNode{Value: i, Kind: reflect.Int64, Text: strconv.FormatInt(i, 10)}, nil
+	}
+
+	if u, ok := toUint(value); ok {
+		if u <= math.MaxInt64 {
+			return NewLiteral(int64(u))
+		}
+		return &LiteralNode{Value: u, Kind: reflect.Uint64, Text: strconv.FormatUint(u, 10)}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported value type %T", value)
+}
@@ -0,0 +1,149 @@
+package qfv
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestKeysetFilter(t *testing.T) {
+	parser := NewSortParser([]string{"name", "age", "id", "score", "active", "created_at", "email"})
+	createdAt := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+	row := map[string]any{"name": "O'Brien", "age": 42, "id": int64(7), "score": 9.5, "active": true, "created_at": createdAt}
+
+	tests := []struct {
+		name     string
+		sort     string
+		expanded bool
+		want     string
+		wantErr  string
+	}{
+		{
+			name: "single field",
+			sort: "id ASC",
+			want: "((id > 7))",
+		},
+		{
+			name: "row comparison",
+			sort: "name DESC, id DESC",
+			want: "(((name, id) < ('O''Brien', 7)))",
+		},
+		{
+			name: "mixed directions",
+			sort: "age DESC, score ASC, id ASC",
+			want: "(((((age < 42)) OR (((age = 42) AND (score > 9.5)))) OR ((((age = 42) AND (score = 9.5)) AND (id > 7)))))",
+		},
+		{
+			name:     "expanded",
+			sort:     "created_at ASC, active ASC",
+			expanded: true,
+			want:     "((((created_at > '2024-05-01T10:30:00Z')) OR (((created_at = '2024-05-01T10:30:00Z') AND (active > true)))))",
+		},
+		{
+			name:    "missing value",
+			sort:    "name ASC, email ASC",
+			wantErr: "missing value for sort field email",
+		},
+		{
+			name:    "nulls ordering",
+			sort:    "name ASC NULLS FIRST",
+			wantErr: "keyset pagination does not support NULLS FIRST on field name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sort, err := parser.Parse(tt.sort)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			keyset := KeysetFilter
+			if tt.expanded {
+				keyset = ExpandedKeysetFilter
+			}
+
+			got, err := keyset(sort, row)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("KeysetFilter() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("KeysetFilter() error = %v", err)
+			}
+
+			if got.String() != tt.want {
+				t.Errorf("KeysetFilter() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("empty sort", func(t *testing.T) {
+		if _, err := KeysetFilter(SortNode{}, row); err == nil {
+			t.Errorf("KeysetFilter() error = nil, want error")
+		}
+	})
+}
+
+func TestKeysetFilter_RestrictFilter(t *testing.T) {
+	filterParser := NewFilterParser([]string{"status"})
+	sortParser := NewSortParser([]string{"id"})
+
+	user, err := filterParser.Parse("status = 'active'")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	sort, err := sortParser.Parse("id DESC")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	keyset, err := KeysetFilter(sort, map[string]any{"id": 10})
+	if err != nil {
+		t.Fatalf("KeysetFilter() error = %v", err)
+	}
+
+	want := "(((status = 'active')) AND ((id < 10)))"
+	if got := RestrictFilter(user, keyset).String(); got != want {
+		t.Errorf("RestrictFilter() = %s, want %s", got, want)
+	}
+}
+
+func TestNewLiteral(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   any
+		want    *LiteralNode
+		wantErr bool
+	}{
+		{name: "string", value: "it's", want: &LiteralNode{Value: "it's", Kind: reflect.String, Text: "'it''s'"}},
+		{name: "int", value: int32(-3), want: &LiteralNode{Value: int64(-3), Kind: reflect.Int64, Text: "-3"}},
+		{name: "unsigned int", value: uint32(7), want: &LiteralNode{Value: int64(7), Kind: reflect.Int64, Text: "7"}},
+		{name: "largest int64 as uint64", value: uint64(1<<63 - 1), want: &LiteralNode{Value: int64(1<<63 - 1), Kind: reflect.Int64, Text: "9223372036854775807"}},
+		{name: "uint64 beyond int64", value: uint64(1 << 63), want: &LiteralNode{Value: uint64(1 << 63), Kind: reflect.Uint64, Text: "9223372036854775808"}},
+		{name: "float", value: float32(0.5), want: &LiteralNode{Value: 0.5, Kind: reflect.Float64, Text: "0.5"}},
+		{name: "bool", value: false, want: &LiteralNode{Value: false, Kind: reflect.Bool, Text: "false"}},
+		{
+			name:  "time",
+			value: time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC),
+			want:  &LiteralNode{Value: "2024-01-02T03:04:05.000000006Z", Kind: reflect.String, Text: "'2024-01-02T03:04:05.000000006Z'"},
+		},
+		{name: "unsupported", value: []int{1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewLiteral(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewLiteral() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewLiteral() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
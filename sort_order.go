@@ -0,0 +1,383 @@
+package qfv
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// SQL renders the sort expression for the dialect (e.g. "created_at" DESC NULLS LAST),
+// without the ORDER BY keywords. Nested fields are rendered as paths in JSON columns.
+func (n SortNode) SQL(d Dialect) (string, error) {
+	parts := make([]string, 0, len(n.Fields))
+	for _, f := range n.Fields {
+		sql, err := f.SQL(d)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, sql)
+	}
+
+	return strings.Join(parts, ", "), nil
+}
+
+// SQL renders the sort field for the dialect, or its function call rendered with
+// the templates of the functions. MySQL has no NULLS FIRST and NULLS LAST, they
+// are emulated by sorting on IS NULL first.
+func (n SortFieldNode) SQL(d Dialect) (string, error) {
+	var field string
+	var err error
+	if n.Function != nil {
+		field, err = sqlExpression(d, n.Function)
+	} else {
+		field, err = quoteIdentifier(d, n.Field)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	sql := field + " " + n.Direction.String()
+	switch {
+	case n.Nulls == NullsDefault:
+		return sql, nil
+	case d == DialectMySQL && n.Nulls == NullsFirst:
+		return field + " IS NULL DESC, " + sql, nil
+	case d == DialectMySQL:
+		return field + " IS NULL, " + sql, nil
+	default:
+		return sql + " NULLS " + n.Nulls.String(), nil
+	}
+}
+
+// quoteIdentifier quotes the field name for the dialect. The first segment of a
+// nested field is a JSON column and the others its keys, rendered with the JSON
+// operators of the dialect (e.g. "address"->'city' in Postgres).
+func quoteIdentifier(d Dialect, name string) (string, error) {
+	var quote string
+	switch d {
+	case DialectPostgres, DialectSQLite:
+		quote = `"`
+	case DialectMySQL:
+		quote = "`"
+	default:
+		return "", fmt.Errorf("unsupported dialect %s", d)
+	}
+
+	path := ParseFieldPath(name)
+	column := quote + strings.ReplaceAll(path[0], quote, quote+quote) + quote
+	if !path.IsNested() {
+		return column, nil
+	}
+
+	keys := path[1:]
+	for _, key := range keys {
+		if strings.ContainsAny(key, `"\`) {
+			return "", fmt.Errorf("unsupported key %q in nested field %s", key, name)
+		}
+	}
+
+	switch d {
+	case DialectPostgres:
+		for _, key := range keys {
+			column += "->" + quoteString(d, key) // jsonb values compare by type and value
+		}
+		return column, nil
+	case DialectMySQL:
+		return column + "->" + quoteString(d, jsonPath(keys)), nil
+	default:
+		return "json_extract(" + column + ", " + quoteString(d, jsonPath(keys)) + ")", nil
+	}
+}
+
+// jsonPath returns the JSON path of the keys (e.g. $.address.city), quoting the
+// keys that are not made of letters, digits and underscores
+func jsonPath(keys []string) string {
+	var sb strings.Builder
+	sb.WriteString("$")
+	for _, key := range keys {
+		if isPathSegment(key) {
+			sb.WriteString("." + key)
+		} else {
+			sb.WriteString(`."` + key + `"`)
+		}
+	}
+
+	return sb.String()
+}
+
+// quoteString renders the value as a string literal for the dialect. MySQL
+// treats backslashes as escapes in string literals, they are doubled too.
+func quoteString(d Dialect, value string) string {
+	if d == DialectMySQL {
+		value = strings.ReplaceAll(value, `\`, `\\`)
+	}
+
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// Compare compares two rows, keyed by field name, in the sort order. It returns
+// a negative number when a sorts before b, a positive number when a sorts after b
+// and zero otherwise, so it can be used with slices.SortStableFunc. Nested fields
+// are looked up in nested maps, and missing fields are null. Function calls are
+// evaluated with Function.Eval, a null argument or a value the function cannot
+// evaluate gives null, as do functions without Eval (see Comparable).
+func (n SortNode) Compare(a, b map[string]any) int {
+	for _, f := range n.Fields {
+		if c := f.compare(f.value(a), f.value(b)); c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
+
+// Comparable returns an error if Compare cannot evaluate the sort in memory,
+// because a sort field calls a function without Eval
+func (n SortNode) Comparable() error {
+	for _, f := range n.Fields {
+		if f.Function == nil {
+			continue
+		}
+
+		var err error
+		Inspect(f.Function, func(node Node) bool {
+			if call, ok := node.(*FunctionCallNode); ok && call.Function.Eval == nil && err == nil {
+				err = fmt.Errorf("sort field %s: function %s cannot be evaluated in memory", f.Field, call.Name)
+			}
+			return err == nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// value returns the value of the sort field in the row, evaluating its function call
+func (n SortFieldNode) value(row map[string]any) any {
+	if n.Function == nil {
+		return rowValue(row, n.Field)
+	}
+
+	return evaluate(n.Function, row)
+}
+
+// evaluate evaluates the sort expression on the row, returning nil for null
+func evaluate(node Node, row map[string]any) any {
+	switch n := node.(type) {
+	case *IdentifierNode:
+		return rowValue(row, n.Name)
+	case *LiteralNode:
+		if n.Kind == reflect.String {
+			return stringValue(n)
+		}
+		return n.Value
+	case *FunctionCallNode:
+		if n.Function.Eval == nil {
+			return nil // Sort input comes from clients, it must not crash the caller
+		}
+
+		args := make([]any, len(n.Args))
+		for i, arg := range n.Args {
+			if args[i] = evaluate(arg, row); args[i] == nil {
+				return nil // Functions of null are null, like in SQL
+			}
+		}
+
+		value, err := n.Function.Eval(args)
+		if err != nil {
+			return nil
+		}
+		return value
+	default:
+		return nil
+	}
+}
+
+// compare compares two values of the field in the sort order
+func (n SortFieldNode) compare(a, b any) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil || b == nil:
+		// Like in Postgres, nulls are larger than any value by default
+		nullsFirst := n.Nulls == NullsFirst || (n.Nulls == NullsDefault && n.Direction == SortDesc)
+		if (a == nil) == nullsFirst {
+			return -1
+		}
+		return 1
+	}
+
+	c := compareValues(a, b)
+	if n.Direction == SortDesc {
+		return -c
+	}
+
+	return c
+}
+
+// rowValue returns the value of the field in the row, following nested maps for dotted paths
+func rowValue(row map[string]any, field string) any {
+	if v, ok := row[field]; ok {
+		return v
+	}
+
+	var value any = row
+	for _, segment := range strings.Split(field, ".") {
+		m, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = m[segment]
+	}
+
+	return value
+}
+
+// compareValues compares two non-null values of the same kind, numbers of
+// different types compare by value and other mixed kinds by their text
+func compareValues(a, b any) int {
+	switch a := a.(type) {
+	case string:
+		if b, ok := b.(string); ok {
+			return strings.Compare(a, b)
+		}
+	case bool:
+		if b, ok := b.(bool); ok {
+			switch {
+			case a == b:
+				return 0
+			case b:
+				return -1
+			default:
+				return 1
+			}
+		}
+	case time.Time:
+		if b, ok := b.(time.Time); ok {
+			return a.Compare(b)
+		}
+	}
+
+	if x, ok := toInteger(a); ok {
+		if y, ok := toInteger(b); ok {
+			return x.compare(y) // Exact for large integers such as IDs
+		}
+	}
+
+	if x, ok := toFloat(a); ok {
+		if y, ok := toFloat(b); ok {
+			return cmp.Compare(x, y)
+		}
+	}
+
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// toInt converts a signed integer value to int64
+func toInt(v any) (int64, bool) {
+	switch v := v.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+// toUint converts an unsigned integer value to uint64
+func toUint(v any) (uint64, bool) {
+	switch v := v.(type) {
+	case uint:
+		return uint64(v), true
+	case uint8:
+		return uint64(v), true
+	case uint16:
+		return uint64(v), true
+	case uint32:
+		return uint64(v), true
+	case uint64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+// integer is a signed or unsigned integer value, as its sign and magnitude
+type integer struct {
+	negative  bool
+	magnitude uint64
+}
+
+// toInteger converts a signed or unsigned integer value to an integer
+func toInteger(v any) (integer, bool) {
+	if i, ok := toInt(v); ok {
+		if i < 0 {
+			return integer{negative: true, magnitude: uint64(-(i + 1)) + 1}, true
+		}
+		return integer{magnitude: uint64(i)}, true
+	}
+
+	if u, ok := toUint(v); ok {
+		return integer{magnitude: u}, true
+	}
+
+	return integer{}, false
+}
+
+// compare compares two integers
+func (x integer) compare(y integer) int {
+	switch {
+	case x.negative != y.negative:
+		if x.negative {
+			return -1
+		}
+		return 1
+	case x.negative:
+		return cmp.Compare(y.magnitude, x.magnitude)
+	default:
+		return cmp.Compare(x.magnitude, y.magnitude)
+	}
+}
+
+// toFloat converts a numeric value to float64
+func toFloat(v any) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
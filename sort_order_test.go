@@ -0,0 +1,221 @@
+package qfv
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSortNode_SQL(t *testing.T) {
+	parser := NewSortParser([]string{"name", "created_at", "address.city", "first-name", "meta.o'k"})
+
+	tests := []struct {
+		name    string
+		input   string
+		dialect Dialect
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "postgres",
+			input:   "created_at DESC NULLS LAST, name ASC",
+			dialect: DialectPostgres,
+			want:    `"created_at" DESC NULLS LAST, "name" ASC`,
+		},
+		{
+			name:    "sqlite",
+			input:   "created_at asc nulls first",
+			dialect: DialectSQLite,
+			want:    `"created_at" ASC NULLS FIRST`,
+		},
+		{
+			name:    "mysql nulls last",
+			input:   "created_at DESC NULLS LAST",
+			dialect: DialectMySQL,
+			want:    "`created_at` IS NULL, `created_at` DESC",
+		},
+		{
+			name:    "mysql nulls first",
+			input:   "created_at ASC NULLS FIRST, name DESC",
+			dialect: DialectMySQL,
+			want:    "`created_at` IS NULL DESC, `created_at` ASC, `name` DESC",
+		},
+		{
+			name:    "quoted and nested fields",
+			input:   `"first-name" ASC, address.city DESC`,
+			dialect: DialectPostgres,
+			want:    `"first-name" ASC, "address"->'city' DESC`,
+		},
+		{
+			name:    "mysql nested field",
+			input:   "address.city DESC NULLS LAST",
+			dialect: DialectMySQL,
+			want:    "`address`->'$.city' IS NULL, `address`->'$.city' DESC",
+		},
+		{
+			name:    "sqlite nested field",
+			input:   "address.city ASC",
+			dialect: DialectSQLite,
+			want:    `json_extract("address", '$.city') ASC`,
+		},
+		{
+			name:    "quoted nested key",
+			input:   `"meta.o'k" ASC`,
+			dialect: DialectMySQL,
+			want:    "`meta`->'$.\"o''k\"' ASC",
+		},
+		{
+			name:    "unknown dialect",
+			input:   "name ASC",
+			dialect: Dialect("oracle"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, err := parser.Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			got, err := node.SQL(tt.dialect)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SQL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("SQL() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortNode_Compare(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	rows := []map[string]any{
+		{"id": 1, "score": 2.5, "created_at": day(3), "address": map[string]any{"city": "Paris"}},
+		{"id": 2, "score": nil, "created_at": day(1)},
+		{"id": 3, "score": int64(7), "created_at": nil, "address": map[string]any{"city": "Berlin"}},
+		{"id": 4, "score": 2.5, "created_at": day(2), "address": map[string]any{"city": nil}},
+	}
+
+	parser := NewSortParser([]string{"id", "score", "created_at", "address.city"})
+
+	tests := []struct {
+		name  string
+		input string
+		want  []int
+	}{
+		{name: "ascending nulls last by default", input: "score ASC, id DESC", want: []int{4, 1, 3, 2}},
+		{name: "descending nulls first by default", input: "score DESC, id ASC", want: []int{2, 3, 1, 4}},
+		{name: "ascending nulls first", input: "score ASC NULLS FIRST, id ASC", want: []int{2, 1, 4, 3}},
+		{name: "descending nulls last", input: "created_at DESC NULLS LAST", want: []int{1, 4, 2, 3}},
+		{name: "nested field", input: "address.city ASC NULLS FIRST, id ASC", want: []int{2, 4, 3, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, err := parser.Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			sorted := slices.Clone(rows)
+			slices.SortStableFunc(sorted, node.Compare)
+
+			var ids []int
+			for _, row := range sorted {
+				ids = append(ids, row["id"].(int))
+			}
+
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("sorted ids = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareValues_Integers(t *testing.T) {
+	tests := []struct {
+		a, b any
+		want int
+	}{
+		{uint64(1 << 63), int64(1<<63 - 1), 1},
+		{uint64(1<<63 + 1), uint64(1 << 63), 1},
+		{int64(-1), uint64(1 << 63), -1},
+		{int64(-1 << 63), int64(-1<<63 + 1), -1},
+		{uint8(7), 7, 0},
+	}
+
+	for _, tt := range tests {
+		if got := compareValues(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareValues(%v, %v) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSortNode_CompareFunctions(t *testing.T) {
+	rows := []map[string]any{
+		{"id": 1, "name": "bob"},
+		{"id": 2, "name": "Alice"},
+		{"id": 3, "name": nil},
+		{"id": 4, "name": "Carol"},
+		{"id": 5, "name": "al"},
+	}
+
+	parser := NewSortParser([]string{"id", "name"})
+	for _, fn := range []Function{FunctionLower, FunctionLength, {Name: "soundex", Args: []ValueType{ValueTypeString}}} {
+		if err := parser.RegisterFunction(fn); err != nil {
+			t.Fatalf("RegisterFunction() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  []int
+	}{
+		{name: "raw values", input: "name ASC, id ASC", want: []int{2, 4, 5, 1, 3}},
+		{name: "lower", input: "lower(name) ASC, id ASC", want: []int{5, 2, 1, 4, 3}},
+		{name: "length", input: "length(name) DESC, id ASC", want: []int{3, 2, 4, 1, 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, err := parser.Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if err := node.Comparable(); err != nil {
+				t.Fatalf("Comparable() error = %v", err)
+			}
+
+			sorted := slices.Clone(rows)
+			slices.SortStableFunc(sorted, node.Compare)
+
+			var ids []int
+			for _, row := range sorted {
+				ids = append(ids, row["id"].(int))
+			}
+
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("sorted ids = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+
+	node, err := parser.Parse("soundex(name) ASC")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := "sort field name: function soundex cannot be evaluated in memory"
+	if err := node.Comparable(); err == nil || err.Error() != want {
+		t.Errorf("Comparable() error = %v, want %q", err, want)
+	}
+
+	// Functions without Eval are null, the client input must not panic
+	if c := node.Compare(rows[0], rows[1]); c != 0 {
+		t.Errorf("Compare() = %d, want 0", c)
+	}
+}
@@ -2,20 +2,29 @@ package qfv
 
 import (
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
+	"text/scanner"
 )
 
 type QFVSortError struct {
 	Field   string
 	Message string
+	Pos     scanner.Position // Position of the error in the input, if known
 }
 
 func (e *QFVSortError) Error() string {
+	var at string
+	if e.Pos.IsValid() {
+		at = fmt.Sprintf(" at %d:%d", e.Pos.Line, e.Pos.Column)
+	}
+
 	if e.Field != "" {
-		return fmt.Sprintf("error on field '%s': %s", e.Field, e.Message)
+		return fmt.Sprintf("error on field '%s'%s: %s", e.Field, at, e.Message)
 	}
 
-	return fmt.Sprintf("error: %s", e.Message)
+	return fmt.Sprintf("error%s: %s", at, e.Message)
 }
 
 // SortDirection represents the sorting direction in sort expressions
@@ -30,10 +39,39 @@ func (sd SortDirection) String() string {
 	return string(sd)
 }
 
+// NullsOrder represents the position of null values in sort expressions
+type NullsOrder string
+
+const (
+	NullsDefault NullsOrder = ""      // Dialect default in SQL, in memory nulls sort as larger than any value
+	NullsFirst   NullsOrder = "FIRST" // NULLS FIRST
+	NullsLast    NullsOrder = "LAST"  // NULLS LAST
+)
+
+func (no NullsOrder) String() string {
+	return string(no)
+}
+
 // SortFieldNode represents a single field in the sort expression
 type SortFieldNode struct {
-	Field     string
+	Field     string    // Canonical name of the field
+	Alias     string    // Name used in the input when it differs from Field (e.g. an alias), empty otherwise
+	Path      FieldPath // Segments of the canonical name when it is a dotted path, nil otherwise
 	Direction SortDirection
+	Nulls     NullsOrder // Position of null values, NullsDefault when not given
+
+	// Function is the call sorted by instead of the field (e.g. lower(last_name)),
+	// nil when sorting by the field itself
+	Function *FunctionCallNode
+}
+
+// Original returns the name of the field as written in the input
+func (n SortFieldNode) Original() string {
+	if n.Alias != "" {
+		return n.Alias
+	}
+
+	return n.Field
 }
 
 func (n SortFieldNode) Type() NodeType {
@@ -51,74 +89,399 @@ func (n SortNode) Type() NodeType {
 
 // SortParser parses the query parameter for sorting
 type SortParser struct {
-	allowedFields map[string]any // any because don't allocate memory for struct{}
+	fields           fieldSet
+	functions        functionRegistry
+	prefixes         bool
+	defaultDirection SortDirection
+	directions       map[string][]SortDirection // Directions allowed per field, all when missing
+	maxFields        int
+	defaultSort      *SortNode
+	tiebreakers      []string
+	uniqueFields     map[string]any // any because don't allocate memory for struct{}
+	err              error          // Invalid configuration, returned by every parse
+}
+
+// SortOption configures a SortParser
+type SortOption interface {
+	applySort(*SortParser)
+}
+
+// sortOption adapts a function to the SortOption interface
+type sortOption func(*SortParser)
+
+func (o sortOption) applySort(p *SortParser) { o(p) }
+
+// WithSortPrefixes accepts +field and -field (e.g. -created_at,name) for
+// ascending and descending fields, as in JSON:API
+func WithSortPrefixes(enabled bool) SortOption {
+	return sortOption(func(p *SortParser) {
+		p.prefixes = enabled
+	})
+}
+
+// WithDefaultDirection sets the direction of the fields given without one
+// (e.g. name), by default a direction is required
+func WithDefaultDirection(direction SortDirection) SortOption {
+	return sortOption(func(p *SortParser) {
+		p.defaultDirection = direction
+	})
 }
 
 // NewSortParser creates a new parser with the allowed fields for sorting
-func NewSortParser(allowedFields []string) *SortParser {
-	sortFields := make(map[string]any, len(allowedFields))
+func NewSortParser(allowedFields []string, opts ...SortOption) *SortParser {
+	p := &SortParser{
+		fields:    newFieldSet(allowedFields),
+		functions: make(functionRegistry),
+	}
+
+	for _, opt := range opts {
+		opt.applySort(p)
+	}
+	p.err = p.validate()
+
+	return p
+}
+
+// validate resolves the fields named by the options to their canonical names,
+// returning an error if one is unknown or the default sort breaks the rules
+func (p *SortParser) validate() error {
+	if p.directions != nil {
+		directions := make(map[string][]SortDirection, len(p.directions))
+		for _, name := range slices.Sorted(maps.Keys(p.directions)) {
+			canonical, ok := p.fields.known(name)
+			if !ok {
+				return fmt.Errorf("directions of field %s: unknown field", name)
+			}
+			directions[canonical] = p.directions[name]
+		}
+		p.directions = directions
+	}
+
+	tiebreakers := make([]string, 0, len(p.tiebreakers))
+	for _, name := range p.tiebreakers {
+		canonical, ok := p.fields.known(name)
+		if !ok {
+			return fmt.Errorf("tiebreaker %s: unknown field", name)
+		}
+		if !slices.Contains(tiebreakers, canonical) {
+			tiebreakers = append(tiebreakers, canonical)
+		}
+	}
+	p.tiebreakers = tiebreakers
 
-	for _, f := range allowedFields {
-		sortFields[f] = struct{}{}
+	if p.uniqueFields != nil {
+		unique := make(map[string]any, len(p.uniqueFields))
+		for _, name := range slices.Sorted(maps.Keys(p.uniqueFields)) {
+			canonical, ok := p.fields.known(name)
+			if !ok {
+				return fmt.Errorf("unique sort field %s: unknown field", name)
+			}
+			unique[canonical] = struct{}{}
+		}
+		p.uniqueFields = unique
 	}
 
-	return &SortParser{
-		allowedFields: sortFields,
+	if p.defaultSort != nil {
+		if err := p.resolveDefaultSort(); err != nil {
+			return err
+		}
 	}
+
+	return nil
+}
+
+// resolveDefaultSort checks the default sort against the rules applied to the input
+func (p *SortParser) resolveDefaultSort() error {
+	fields := slices.Clone(p.defaultSort.Fields) // The option may configure other parsers
+	if len(fields) == 0 {
+		return fmt.Errorf("default sort: no fields")
+	}
+	if p.maxFields > 0 && len(fields) > p.maxFields {
+		return fmt.Errorf("default sort: too many sort fields, maximum is %d", p.maxFields)
+	}
+
+	seen := make(map[string]any, len(fields))
+	for i, field := range fields {
+		canonical, ok := p.fields.known(field.Field)
+		if !ok {
+			return fmt.Errorf("default sort: unknown field %s", field.Field)
+		}
+
+		if field.Direction != SortAsc && field.Direction != SortDesc {
+			return fmt.Errorf("default sort: invalid sort direction %q for field %s", field.Direction, field.Field)
+		}
+		if allowed, ok := p.directions[canonical]; ok && !slices.Contains(allowed, field.Direction) {
+			return fmt.Errorf("default sort: sort direction %s not allowed for field %s", field.Direction, field.Field)
+		}
+
+		key := canonical
+		if field.Function != nil {
+			key = field.Function.String()
+		}
+		if _, dup := seen[key]; dup {
+			return fmt.Errorf("default sort: duplicate sort field %s", field.Field)
+		}
+		seen[key] = struct{}{}
+
+		fields[i].Field, fields[i].Alias, fields[i].Path = canonical, "", nil
+		if path := ParseFieldPath(canonical); path.IsNested() {
+			fields[i].Path = path
+		}
+	}
+	p.defaultSort = &SortNode{Fields: fields}
+
+	return nil
+}
+
+// WithFieldDirections restricts the directions the field can be sorted in
+// (e.g. score only descending). The field may be an alias, and unknown fields
+// make every parse fail.
+func WithFieldDirections(field string, directions ...SortDirection) SortOption {
+	return sortOption(func(p *SortParser) {
+		if p.directions == nil {
+			p.directions = make(map[string][]SortDirection)
+		}
+		p.directions[field] = directions
+	})
+}
+
+// WithMaxSortFields limits the number of fields of a sort expression, zero means no limit.
+// Tiebreakers (see WithTiebreakers) are appended after the check and do not count.
+func WithMaxSortFields(n int) SortOption {
+	return sortOption(func(p *SortParser) {
+		p.maxFields = n
+	})
+}
+
+// WithDefaultSort sets the sort returned for an empty parameter, instead of the
+// empty input expression error. Its fields are resolved to their canonical names,
+// and a default sort the parser would reject makes every parse fail.
+func WithDefaultSort(node SortNode) SortOption {
+	node.Fields = slices.Clone(node.Fields)
+	return sortOption(func(p *SortParser) {
+		p.defaultSort = &node
+	})
+}
+
+// WithTiebreakers sets fields that together are unique (e.g. id), appended to every
+// sort that does not already define a total order, so that pagination is stable.
+// They are sorted in the direction of the last requested field, and do not count
+// toward WithMaxSortFields. Fields may be aliases, unknown fields make every parse fail.
+func WithTiebreakers(fields ...string) SortOption {
+	return sortOption(func(p *SortParser) {
+		p.tiebreakers = slices.Clone(fields)
+	})
+}
+
+// WithUniqueSortFields sets other unique fields (e.g. email), a sort including
+// any of them already defines a total order and gets no tiebreakers. Fields may be
+// aliases, unknown fields make every parse fail.
+func WithUniqueSortFields(fields ...string) SortOption {
+	return sortOption(func(p *SortParser) {
+		if p.uniqueFields == nil {
+			p.uniqueFields = make(map[string]any, len(fields))
+		}
+		for _, f := range fields {
+			p.uniqueFields[f] = struct{}{}
+		}
+	})
 }
 
 // Parse parses the sort parameter
 func (p *SortParser) Parse(input string) (SortNode, error) {
+	return p.ParseWithRoles(input)
+}
+
+// ParseWithRoles parses the sort parameter for a caller with the given roles,
+// which restrict the fields of the registry (see WithFieldRegistry)
+func (p *SortParser) ParseWithRoles(input string, roles ...string) (SortNode, error) {
+	if p.err != nil {
+		return SortNode{}, p.err
+	}
+
+	if p.defaultSort != nil && strings.TrimSpace(input) == "" {
+		return p.withTiebreakers(SortNode{Fields: slices.Clone(p.defaultSort.Fields)}), nil
+	}
+
 	if input == "" {
 		return SortNode{}, &QFVSortError{Message: "empty input expression"}
 	}
 
-	parts := strings.Split(input, ",")
-	fields := make([]SortFieldNode, 0, len(parts))
+	in := &sortInput{lexer: NewLexer(input), roles: roles, seen: make(map[string]any)}
+	in.lexer.Parse()
 
-	for _, part := range parts {
-		part = strings.TrimSpace(part)
-		if part == "" {
-			return SortNode{}, &QFVSortError{Field: part, Message: "empty sort expression"}
+	var fields []SortFieldNode
+	for {
+		if p.maxFields > 0 && len(fields) == p.maxFields {
+			return SortNode{}, &QFVSortError{Message: fmt.Sprintf("too many sort fields, maximum is %d", p.maxFields), Pos: in.lexer.Peek().Pos}
 		}
 
-		sortParts := strings.Fields(part)
-		if len(sortParts) == 0 {
-			return SortNode{}, &QFVSortError{Field: part, Message: "invalid sort expression"}
+		field, err := p.parseSortField(in)
+		if err != nil {
+			return SortNode{}, err
 		}
+		fields = append(fields, field)
 
-		if len(sortParts) > 2 {
-			return SortNode{}, &QFVSortError{Field: part, Message: "too many sort expressions"}
+		if in.lexer.Next().Type == TokenEOF {
+			break
 		}
+	}
+
+	return p.withTiebreakers(SortNode{Fields: fields}), nil
+}
+
+// withTiebreakers appends the tiebreakers missing from the sort,
+// unless it already includes a unique field
+func (p *SortParser) withTiebreakers(node SortNode) SortNode {
+	if len(p.tiebreakers) == 0 {
+		return node
+	}
+
+	present := make(map[string]any, len(node.Fields))
+	for _, f := range node.Fields {
+		if f.Function != nil {
+			continue // Function values may collide even for unique fields (e.g. lower(email))
+		}
+		if _, unique := p.uniqueFields[f.Field]; unique {
+			return node
+		}
+		present[f.Field] = struct{}{}
+	}
+
+	direction := SortAsc
+	if len(node.Fields) > 0 {
+		direction = node.Fields[len(node.Fields)-1].Direction
+	}
 
-		fieldName := sortParts[0]
-		if _, exists := p.allowedFields[fieldName]; !exists {
-			return SortNode{}, &QFVSortError{Field: fieldName, Message: "field not allowed for sorting"}
+	for _, field := range p.tiebreakers {
+		if _, ok := present[field]; ok {
+			continue
 		}
 
-		direction := SortAsc
-		if len(sortParts) == 1 {
-			return SortNode{}, &QFVSortError{Field: fieldName, Message: "missing sort direction after field"}
+		tiebreaker := SortFieldNode{Field: field, Direction: direction}
+		if path := ParseFieldPath(field); path.IsNested() {
+			tiebreaker.Path = path
 		}
+		node.Fields = append(node.Fields, tiebreaker)
+	}
 
-		if len(sortParts) > 1 {
-			dirStr := strings.ToUpper(sortParts[1])
+	return node
+}
 
-			switch dirStr {
-			case SortDesc.String():
-				direction = SortDesc
-			case SortAsc.String():
-				direction = SortAsc
-			default:
-				return SortNode{}, &QFVSortError{Field: fieldName, Message: "invalid sort direction"}
-			}
+// sortInput holds the state of a sort parameter being parsed
+type sortInput struct {
+	lexer *Lexer
+	roles []string
+	seen  map[string]any // Sorted fields, keyed by canonical name or function call
+}
+
+// parseSortField parses a sort field up to the next comma or the end of the input:
+// an optionally prefixed field or function call, followed by the optional direction
+// and nulls ordering (e.g. name DESC NULLS LAST)
+func (p *SortParser) parseSortField(in *sortInput) (SortFieldNode, error) {
+	tok := in.lexer.Next()
+	if tok.Type == TokenComma || tok.Type == TokenEOF {
+		return SortFieldNode{}, &QFVSortError{Message: "empty sort expression", Pos: tok.Pos}
+	}
+
+	var prefix SortDirection
+	if p.prefixes && tok.Type == TokenIllegal && (tok.Value == "+" || tok.Value == "-") {
+		if next := in.lexer.Peek(); next.Pos.Offset != tok.Pos.Offset+1 || next.Type == TokenComma || next.Type == TokenEOF {
+			return SortFieldNode{}, &QFVSortError{Field: tok.Value, Message: "missing field after sort prefix", Pos: tok.Pos}
+		}
+
+		prefix = SortAsc
+		if tok.Value == "-" {
+			prefix = SortDesc
+		}
+		tok = in.lexer.Next()
+	}
+
+	var field SortFieldNode
+	var fieldName, key string
+	switch name, ok := fieldToken(tok); {
+	case tok.Type == TokenIdentifier && in.lexer.Peek().Type == TokenLPAREN:
+		call, id, err := p.parseSortCall(in, tok)
+		if err != nil {
+			return SortFieldNode{}, err
+		}
+		field.Field, field.Function = id.Name, call
+		fieldName, key = id.Original(), call.String()
+	case ok:
+		canonical, exists := p.fields.resolve(name, in.roles)
+		if !exists {
+			return SortFieldNode{}, &QFVSortError{Field: name, Message: "field not allowed for sorting", Pos: tok.Pos}
+		}
+		field.Field = canonical
+		fieldName, key = name, canonical
+	case tok.Type == TokenIllegal:
+		return SortFieldNode{}, &QFVSortError{Field: tok.Value, Message: illegalTokenMessage(tok), Pos: tok.Pos}
+	default:
+		return SortFieldNode{}, &QFVSortError{Field: tok.Value, Message: "expected field", Pos: tok.Pos}
+	}
+	pos := tok.Pos
+
+	if next := in.lexer.Peek(); next.Type != TokenComma && next.Type != TokenEOF && !isSortKeyword(next, "NULLS") {
+		tok = in.lexer.Next()
+		switch direction := SortDirection(strings.ToUpper(tok.Value)); {
+		case tok.Type != TokenIdentifier || (direction != SortAsc && direction != SortDesc):
+			return SortFieldNode{}, &QFVSortError{Field: fieldName, Message: "invalid sort direction", Pos: tok.Pos}
+		case prefix != "" && prefix != direction:
+			return SortFieldNode{}, &QFVSortError{Field: fieldName, Message: "sort direction conflicts with prefix", Pos: tok.Pos}
+		default:
+			field.Direction = direction
+		}
+	}
+
+	if next := in.lexer.Peek(); isSortKeyword(next, "NULLS") {
+		in.lexer.Next()
+		tok = in.lexer.Next()
+		switch {
+		case tok.Type == TokenComma || tok.Type == TokenEOF:
+			return SortFieldNode{}, &QFVSortError{Field: fieldName, Message: "missing FIRST or LAST after NULLS", Pos: next.Pos}
+		case isSortKeyword(tok, string(NullsFirst)):
+			field.Nulls = NullsFirst
+		case isSortKeyword(tok, string(NullsLast)):
+			field.Nulls = NullsLast
+		default:
+			return SortFieldNode{}, &QFVSortError{Field: fieldName, Message: "invalid nulls ordering", Pos: tok.Pos}
 		}
+	}
+
+	if next := in.lexer.Peek(); next.Type != TokenComma && next.Type != TokenEOF {
+		return SortFieldNode{}, &QFVSortError{Field: fieldName, Message: "too many sort expressions", Pos: next.Pos}
+	}
+
+	switch {
+	case field.Direction != "":
+	case prefix != "":
+		field.Direction = prefix
+	case p.defaultDirection != "":
+		field.Direction = p.defaultDirection
+	default:
+		return SortFieldNode{}, &QFVSortError{Field: fieldName, Message: "missing sort direction after field", Pos: pos}
+	}
 
-		fields = append(fields, SortFieldNode{
-			Field:     fieldName,
-			Direction: direction,
-		})
+	if allowed, ok := p.directions[field.Field]; ok && !slices.Contains(allowed, field.Direction) {
+		return SortFieldNode{}, &QFVSortError{Field: fieldName, Message: fmt.Sprintf("sort direction %s not allowed for field", field.Direction), Pos: pos}
 	}
 
-	return SortNode{Fields: fields}, nil
+	if _, dup := in.seen[key]; dup {
+		return SortFieldNode{}, &QFVSortError{Field: fieldName, Message: "duplicate sort field", Pos: pos}
+	}
+	in.seen[key] = struct{}{}
+
+	if field.Field != fieldName {
+		field.Alias = fieldName
+	}
+	if path := ParseFieldPath(field.Field); path.IsNested() {
+		field.Path = path
+	}
+
+	return field, nil
+}
+
+// isSortKeyword reports whether the token is the keyword (e.g. NULLS), in any case
+func isSortKeyword(tok Token, keyword string) bool {
+	return tok.Type == TokenIdentifier && strings.EqualFold(tok.Value, keyword)
 }
@@ -1,8 +1,10 @@
 package qfv
 
 import (
+	"errors"
 	"reflect"
 	"testing"
+	"text/scanner"
 )
 
 func TestSortParser_Parse(t *testing.T) {
@@ -45,7 +47,7 @@ func TestSortParser_Parse(t *testing.T) {
 			name:        "single field missing direction",
 			input:       "name",
 			expected:    SortNode{},
-			expectedErr: &QFVSortError{Field: "name", Message: "missing sort direction after field"},
+			expectedErr: &QFVSortError{Field: "name", Message: "missing sort direction after field", Pos: scanner.Position{Line: 1, Column: 1}},
 		},
 		{
 			name:  "single field descending uppercase",
@@ -71,19 +73,19 @@ func TestSortParser_Parse(t *testing.T) {
 			name:        "single field, wrong direction",
 			input:       "name invalid",
 			expected:    SortNode{},
-			expectedErr: &QFVSortError{Field: "name", Message: "invalid sort direction"},
+			expectedErr: &QFVSortError{Field: "name", Message: "invalid sort direction", Pos: scanner.Position{Line: 1, Column: 6}},
 		},
 		{
 			name:        "single field multiple direction",
 			input:       "name asc DESC",
 			expected:    SortNode{},
-			expectedErr: &QFVSortError{Field: "name asc DESC", Message: "too many sort expressions"},
+			expectedErr: &QFVSortError{Field: "name", Message: "too many sort expressions", Pos: scanner.Position{Line: 1, Column: 10}},
 		},
 		{
 			name:        "single field descending with extra comma",
 			input:       "name DESC,",
 			expected:    SortNode{},
-			expectedErr: &QFVSortError{Message: "empty sort expression"},
+			expectedErr: &QFVSortError{Message: "empty sort expression", Pos: scanner.Position{Line: 1, Column: 11}},
 		},
 		{
 			name:  "multiple fields, lowercase and uppercase",
@@ -101,31 +103,67 @@ func TestSortParser_Parse(t *testing.T) {
 			name:        "invalid field",
 			input:       "unknown",
 			expected:    SortNode{},
-			expectedErr: &QFVSortError{Field: "unknown", Message: "field not allowed for sorting"},
+			expectedErr: &QFVSortError{Field: "unknown", Message: "field not allowed for sorting", Pos: scanner.Position{Line: 1, Column: 1}},
 		},
 		{
 			name:        "invalid direction",
 			input:       "name invalid",
 			expected:    SortNode{},
-			expectedErr: &QFVSortError{Field: "name", Message: "invalid sort direction"},
+			expectedErr: &QFVSortError{Field: "name", Message: "invalid sort direction", Pos: scanner.Position{Line: 1, Column: 6}},
 		},
 		{
 			name:        "empty part",
 			input:       "name, ,age",
 			expected:    SortNode{},
-			expectedErr: &QFVSortError{Field: "name", Message: "missing sort direction after field"},
+			expectedErr: &QFVSortError{Field: "name", Message: "missing sort direction after field", Pos: scanner.Position{Line: 1, Column: 1}},
 		},
 		{
 			name:        "invalid sort expression",
 			input:       " ",
 			expected:    SortNode{},
-			expectedErr: &QFVSortError{Message: "empty sort expression"},
+			expectedErr: &QFVSortError{Message: "empty sort expression", Pos: scanner.Position{Line: 1, Column: 2}},
 		},
 		{
 			name:        "single comma",
 			input:       ",",
 			expected:    SortNode{},
-			expectedErr: &QFVSortError{Message: "empty sort expression"},
+			expectedErr: &QFVSortError{Message: "empty sort expression", Pos: scanner.Position{Line: 1, Column: 1}},
+		},
+		{
+			name:  "nulls ordering",
+			input: "name DESC NULLS LAST, age asc nulls first, city ASC",
+			expected: SortNode{
+				Fields: []SortFieldNode{
+					{Field: "name", Direction: SortDesc, Nulls: NullsLast},
+					{Field: "age", Direction: SortAsc, Nulls: NullsFirst},
+					{Field: "city", Direction: SortAsc},
+				},
+			},
+			expectedErr: nil,
+		},
+		{
+			name:        "nulls ordering without position",
+			input:       "name DESC NULLS",
+			expected:    SortNode{},
+			expectedErr: &QFVSortError{Field: "name", Message: "missing FIRST or LAST after NULLS", Pos: scanner.Position{Line: 1, Column: 11}},
+		},
+		{
+			name:        "invalid nulls ordering",
+			input:       "name DESC NULLS MIDDLE",
+			expected:    SortNode{},
+			expectedErr: &QFVSortError{Field: "name", Message: "invalid nulls ordering", Pos: scanner.Position{Line: 1, Column: 17}},
+		},
+		{
+			name:        "nulls ordering without direction",
+			input:       "name NULLS LAST",
+			expected:    SortNode{},
+			expectedErr: &QFVSortError{Field: "name", Message: "missing sort direction after field", Pos: scanner.Position{Line: 1, Column: 1}},
+		},
+		{
+			name:        "too many expressions after nulls ordering",
+			input:       "name DESC NULLS LAST x",
+			expected:    SortNode{},
+			expectedErr: &QFVSortError{Field: "name", Message: "too many sort expressions", Pos: scanner.Position{Line: 1, Column: 22}},
 		},
 	}
 
@@ -153,3 +191,496 @@ func TestSortParser_Parse(t *testing.T) {
 		})
 	}
 }
+
+func TestSortParser_QuotedIdentifiers(t *testing.T) {
+	parser := NewSortParser([]string{"order", "first-name", "a,b", "name", "in"})
+
+	tests := []struct {
+		name        string
+		input       string
+		expected    SortNode
+		expectedErr error
+	}{
+		{
+			name:  "quoted fields",
+			input: "\"first-name\" ASC, `order` desc",
+			expected: SortNode{
+				Fields: []SortFieldNode{
+					{Field: "first-name", Direction: SortAsc},
+					{Field: "order", Direction: SortDesc},
+				},
+			},
+		},
+		{
+			name:  "comma inside quotes",
+			input: `"a,b" DESC, name ASC`,
+			expected: SortNode{
+				Fields: []SortFieldNode{
+					{Field: "a,b", Direction: SortDesc},
+					{Field: "name", Direction: SortAsc},
+				},
+			},
+		},
+		{
+			name:        "unterminated quote",
+			input:       `name ASC, "order ASC`,
+			expectedErr: &QFVSortError{Field: `"order ASC`, Message: "invalid quoted identifier", Pos: scanner.Position{Line: 1, Column: 11}},
+		},
+		{
+			name:        "quoted identifier followed by a word",
+			input:       `"order"x ASC`,
+			expectedErr: &QFVSortError{Field: "order", Message: "invalid sort direction", Pos: scanner.Position{Line: 1, Column: 8}},
+		},
+		{
+			name:        "quoted field not allowed",
+			input:       `"last-name" ASC`,
+			expectedErr: &QFVSortError{Field: "last-name", Message: "field not allowed for sorting", Pos: scanner.Position{Line: 1, Column: 1}},
+		},
+		{
+			name:        "unquoted field with hyphen",
+			input:       `first-name ASC`,
+			expectedErr: &QFVSortError{Field: "first", Message: "field not allowed for sorting", Pos: scanner.Position{Line: 1, Column: 1}},
+		},
+		{
+			name:  "keywords as field names",
+			input: `order DESC, in ASC`,
+			expected: SortNode{
+				Fields: []SortFieldNode{
+					{Field: "order", Direction: SortDesc},
+					{Field: "in", Direction: SortAsc},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := parser.Parse(tt.input)
+			if tt.expectedErr != nil {
+				if err == nil || err.Error() != tt.expectedErr.Error() {
+					t.Fatalf("expected error '%v', got '%v'", tt.expectedErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("expected '%v', got '%v'", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSortParser_Styles(t *testing.T) {
+	allowedFields := []string{"name", "created_at", "first-name"}
+
+	tests := []struct {
+		name        string
+		opts        []SortOption
+		input       string
+		expected    SortNode
+		expectedErr error
+	}{
+		{
+			name:  "prefixes",
+			opts:  []SortOption{WithSortPrefixes(true)},
+			input: "-created_at,+name",
+			expected: SortNode{Fields: []SortFieldNode{
+				{Field: "created_at", Direction: SortDesc},
+				{Field: "name", Direction: SortAsc},
+			}},
+		},
+		{
+			name:  "prefix with matching direction and nulls ordering",
+			opts:  []SortOption{WithSortPrefixes(true)},
+			input: "-created_at DESC NULLS LAST, -name NULLS FIRST",
+			expected: SortNode{Fields: []SortFieldNode{
+				{Field: "created_at", Direction: SortDesc, Nulls: NullsLast},
+				{Field: "name", Direction: SortDesc, Nulls: NullsFirst},
+			}},
+		},
+		{
+			name:  "prefix on quoted identifier",
+			opts:  []SortOption{WithSortPrefixes(true)},
+			input: `-"first-name"`,
+			expected: SortNode{Fields: []SortFieldNode{
+				{Field: "first-name", Direction: SortDesc},
+			}},
+		},
+		{
+			name:        "prefix conflicting with direction",
+			opts:        []SortOption{WithSortPrefixes(true)},
+			input:       "-created_at ASC",
+			expectedErr: &QFVSortError{Field: "created_at", Message: "sort direction conflicts with prefix", Pos: scanner.Position{Line: 1, Column: 13}},
+		},
+		{
+			name:        "prefix without field",
+			opts:        []SortOption{WithSortPrefixes(true)},
+			input:       "- ASC",
+			expectedErr: &QFVSortError{Field: "-", Message: "missing field after sort prefix", Pos: scanner.Position{Line: 1, Column: 1}},
+		},
+		{
+			name:        "prefixes disabled by default",
+			input:       "-created_at",
+			expectedErr: &QFVSortError{Field: "-", Message: "illegal token", Pos: scanner.Position{Line: 1, Column: 1}},
+		},
+		{
+			name:        "prefix still requires direction for unprefixed fields",
+			opts:        []SortOption{WithSortPrefixes(true)},
+			input:       "-created_at,name",
+			expectedErr: &QFVSortError{Field: "name", Message: "missing sort direction after field", Pos: scanner.Position{Line: 1, Column: 13}},
+		},
+		{
+			name:  "default direction",
+			opts:  []SortOption{WithDefaultDirection(SortAsc)},
+			input: "name, created_at DESC",
+			expected: SortNode{Fields: []SortFieldNode{
+				{Field: "name", Direction: SortAsc},
+				{Field: "created_at", Direction: SortDesc},
+			}},
+		},
+		{
+			name:  "default direction with nulls ordering",
+			opts:  []SortOption{WithDefaultDirection(SortDesc)},
+			input: "created_at NULLS LAST",
+			expected: SortNode{Fields: []SortFieldNode{
+				{Field: "created_at", Direction: SortDesc, Nulls: NullsLast},
+			}},
+		},
+		{
+			name:  "prefixes and default direction",
+			opts:  []SortOption{WithSortPrefixes(true), WithDefaultDirection(SortAsc)},
+			input: "-created_at,name",
+			expected: SortNode{Fields: []SortFieldNode{
+				{Field: "created_at", Direction: SortDesc},
+				{Field: "name", Direction: SortAsc},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := NewSortParser(allowedFields, tt.opts...).Parse(tt.input)
+			if tt.expectedErr != nil {
+				if err == nil || err.Error() != tt.expectedErr.Error() {
+					t.Fatalf("expected error '%v', got '%v'", tt.expectedErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("expected '%v', got '%v'", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSortParser_FieldRules(t *testing.T) {
+	allowedFields := []string{"name", "score", "created_at"}
+	defaultSort := SortNode{Fields: []SortFieldNode{{Field: "created_at", Direction: SortDesc}}}
+	registry, err := NewFieldRegistry(FieldDef{Name: "rank", Aliases: []string{"position"}})
+	if err != nil {
+		t.Fatalf("NewFieldRegistry() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		opts        []SortOption
+		input       string
+		expected    SortNode
+		expectedErr error
+	}{
+		{
+			name:  "allowed direction",
+			opts:  []SortOption{WithFieldDirections("score", SortDesc)},
+			input: "score DESC, name ASC",
+			expected: SortNode{Fields: []SortFieldNode{
+				{Field: "score", Direction: SortDesc},
+				{Field: "name", Direction: SortAsc},
+			}},
+		},
+		{
+			name:        "direction not allowed",
+			opts:        []SortOption{WithFieldDirections("score", SortDesc)},
+			input:       "name DESC, score ASC",
+			expectedErr: &QFVSortError{Field: "score", Message: "sort direction ASC not allowed for field", Pos: scanner.Position{Line: 1, Column: 12}},
+		},
+		{
+			name:        "direction from prefix not allowed",
+			opts:        []SortOption{WithFieldDirections("score", SortDesc), WithSortPrefixes(true)},
+			input:       "+score",
+			expectedErr: &QFVSortError{Field: "score", Message: "sort direction ASC not allowed for field", Pos: scanner.Position{Line: 1, Column: 2}},
+		},
+		{
+			name:  "max sort fields",
+			opts:  []SortOption{WithMaxSortFields(2)},
+			input: "score DESC, name ASC",
+			expected: SortNode{Fields: []SortFieldNode{
+				{Field: "score", Direction: SortDesc},
+				{Field: "name", Direction: SortAsc},
+			}},
+		},
+		{
+			name:        "too many sort fields",
+			opts:        []SortOption{WithMaxSortFields(2)},
+			input:       "score DESC, name ASC, created_at ASC",
+			expectedErr: &QFVSortError{Message: "too many sort fields, maximum is 2", Pos: scanner.Position{Line: 1, Column: 23}},
+		},
+		{
+			name:        "duplicate sort field",
+			input:       "name ASC, score DESC, name DESC",
+			expectedErr: &QFVSortError{Field: "name", Message: "duplicate sort field", Pos: scanner.Position{Line: 1, Column: 23}},
+		},
+		{
+			name:     "default sort",
+			opts:     []SortOption{WithDefaultSort(defaultSort)},
+			input:    "",
+			expected: defaultSort,
+		},
+		{
+			name:     "default sort on blank input",
+			opts:     []SortOption{WithDefaultSort(defaultSort)},
+			input:    "  ",
+			expected: defaultSort,
+		},
+		{
+			name:  "default sort replaced by input",
+			opts:  []SortOption{WithDefaultSort(defaultSort)},
+			input: "name ASC",
+			expected: SortNode{Fields: []SortFieldNode{
+				{Field: "name", Direction: SortAsc},
+			}},
+		},
+		{
+			name:        "direction rule given by alias",
+			opts:        []SortOption{WithFieldRegistry(registry), WithFieldDirections("position", SortDesc)},
+			input:       "rank ASC",
+			expectedErr: &QFVSortError{Field: "rank", Message: "sort direction ASC not allowed for field", Pos: scanner.Position{Line: 1, Column: 1}},
+		},
+		{
+			name:        "direction rule on unknown field",
+			opts:        []SortOption{WithFieldDirections("scor", SortDesc)},
+			input:       "name ASC",
+			expectedErr: errors.New("directions of field scor: unknown field"),
+		},
+		{
+			name:  "default sort given by alias",
+			opts:  []SortOption{WithFieldRegistry(registry), WithDefaultSort(SortNode{Fields: []SortFieldNode{{Field: "position", Direction: SortDesc}}})},
+			input: "",
+			expected: SortNode{Fields: []SortFieldNode{
+				{Field: "rank", Direction: SortDesc},
+			}},
+		},
+		{
+			name:        "default sort on unknown field",
+			opts:        []SortOption{WithDefaultSort(SortNode{Fields: []SortFieldNode{{Field: "password", Direction: SortAsc}}})},
+			input:       "",
+			expectedErr: errors.New("default sort: unknown field password"),
+		},
+		{
+			name:        "default sort without direction",
+			opts:        []SortOption{WithDefaultSort(SortNode{Fields: []SortFieldNode{{Field: "name"}}})},
+			input:       "name ASC",
+			expectedErr: errors.New(`default sort: invalid sort direction "" for field name`),
+		},
+		{
+			name:        "default sort breaking a direction rule",
+			opts:        []SortOption{WithFieldDirections("score", SortDesc), WithDefaultSort(SortNode{Fields: []SortFieldNode{{Field: "score", Direction: SortAsc}}})},
+			input:       "",
+			expectedErr: errors.New("default sort: sort direction ASC not allowed for field score"),
+		},
+		{
+			name: "default sort with too many fields",
+			opts: []SortOption{WithMaxSortFields(1), WithDefaultSort(SortNode{Fields: []SortFieldNode{
+				{Field: "score", Direction: SortDesc},
+				{Field: "name", Direction: SortAsc},
+			}})},
+			input:       "",
+			expectedErr: errors.New("default sort: too many sort fields, maximum is 1"),
+		},
+		{
+			name: "default sort with duplicate fields",
+			opts: []SortOption{WithDefaultSort(SortNode{Fields: []SortFieldNode{
+				{Field: "score", Direction: SortDesc},
+				{Field: "score", Direction: SortAsc},
+			}})},
+			input:       "",
+			expectedErr: errors.New("default sort: duplicate sort field score"),
+		},
+		{
+			name:        "empty input without default sort",
+			input:       "",
+			expectedErr: &QFVSortError{Message: "empty input expression"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := NewSortParser(allowedFields, tt.opts...).Parse(tt.input)
+			if tt.expectedErr != nil {
+				if err == nil || err.Error() != tt.expectedErr.Error() {
+					t.Fatalf("expected error '%v', got '%v'", tt.expectedErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("expected '%v', got '%v'", tt.expected, actual)
+			}
+		})
+	}
+
+	// The default sort is copied, changes to the result do not affect the parser
+	parser := NewSortParser(allowedFields, WithDefaultSort(defaultSort))
+	first, _ := parser.Parse("")
+	first.Fields[0].Direction = SortAsc
+	if second, _ := parser.Parse(""); second.Fields[0].Direction != SortDesc {
+		t.Errorf("default sort was modified through a result: %v", second)
+	}
+}
+
+func TestSortParser_Tiebreakers(t *testing.T) {
+	allowedFields := []string{"name", "email", "created_at", "id", "tenant_id"}
+	registry, err := NewFieldRegistry(
+		FieldDef{Name: "uuid", Aliases: []string{"ref"}},
+		FieldDef{Name: "login", Aliases: []string{"username"}},
+	)
+	if err != nil {
+		t.Fatalf("NewFieldRegistry() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		opts     []SortOption
+		input    string
+		expected SortNode
+	}{
+		{
+			name:  "tiebreaker appended with last direction",
+			opts:  []SortOption{WithTiebreakers("id")},
+			input: "name ASC, created_at DESC",
+			expected: SortNode{Fields: []SortFieldNode{
+				{Field: "name", Direction: SortAsc},
+				{Field: "created_at", Direction: SortDesc},
+				{Field: "id", Direction: SortDesc},
+			}},
+		},
+		{
+			name:  "tiebreaker already requested",
+			opts:  []SortOption{WithTiebreakers("id")},
+			input: "id DESC, name ASC",
+			expected: SortNode{Fields: []SortFieldNode{
+				{Field: "id", Direction: SortDesc},
+				{Field: "name", Direction: SortAsc},
+			}},
+		},
+		{
+			name:  "composite tiebreaker partially requested",
+			opts:  []SortOption{WithTiebreakers("tenant_id", "id")},
+			input: "id ASC",
+			expected: SortNode{Fields: []SortFieldNode{
+				{Field: "id", Direction: SortAsc},
+				{Field: "tenant_id", Direction: SortAsc},
+			}},
+		},
+		{
+			name:  "unique field requested",
+			opts:  []SortOption{WithTiebreakers("id"), WithUniqueSortFields("email")},
+			input: "email DESC",
+			expected: SortNode{Fields: []SortFieldNode{
+				{Field: "email", Direction: SortDesc},
+			}},
+		},
+		{
+			name: "tiebreaker appended to default sort",
+			opts: []SortOption{
+				WithTiebreakers("id"),
+				WithDefaultSort(SortNode{Fields: []SortFieldNode{{Field: "created_at", Direction: SortDesc}}}),
+			},
+			input: "",
+			expected: SortNode{Fields: []SortFieldNode{
+				{Field: "created_at", Direction: SortDesc},
+				{Field: "id", Direction: SortDesc},
+			}},
+		},
+		{
+			name:  "tiebreaker given by alias",
+			opts:  []SortOption{WithFieldRegistry(registry), WithTiebreakers("ref")},
+			input: "uuid DESC, name ASC",
+			expected: SortNode{Fields: []SortFieldNode{
+				{Field: "uuid", Direction: SortDesc},
+				{Field: "name", Direction: SortAsc},
+			}},
+		},
+		{
+			name:  "tiebreaker given by alias and appended",
+			opts:  []SortOption{WithFieldRegistry(registry), WithTiebreakers("ref")},
+			input: "name ASC",
+			expected: SortNode{Fields: []SortFieldNode{
+				{Field: "name", Direction: SortAsc},
+				{Field: "uuid", Direction: SortAsc},
+			}},
+		},
+		{
+			name:  "unique field given by alias",
+			opts:  []SortOption{WithFieldRegistry(registry), WithTiebreakers("id"), WithUniqueSortFields("username")},
+			input: "login ASC",
+			expected: SortNode{Fields: []SortFieldNode{
+				{Field: "login", Direction: SortAsc},
+			}},
+		},
+		{
+			name:  "tiebreakers do not count toward the maximum",
+			opts:  []SortOption{WithTiebreakers("id"), WithMaxSortFields(1)},
+			input: "name ASC",
+			expected: SortNode{Fields: []SortFieldNode{
+				{Field: "name", Direction: SortAsc},
+				{Field: "id", Direction: SortAsc},
+			}},
+		},
+		{
+			name:  "no tiebreakers by default",
+			input: "name ASC",
+			expected: SortNode{Fields: []SortFieldNode{
+				{Field: "name", Direction: SortAsc},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := NewSortParser(allowedFields, tt.opts...).Parse(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("expected '%v', got '%v'", tt.expected, actual)
+			}
+		})
+	}
+
+	invalid := []struct {
+		opt  SortOption
+		want string
+	}{
+		{WithTiebreakers("idd"), "tiebreaker idd: unknown field"},
+		{WithUniqueSortFields("mail"), "unique sort field mail: unknown field"},
+	}
+	for _, tt := range invalid {
+		if _, err := NewSortParser(allowedFields, tt.opt).Parse("name ASC"); err == nil || err.Error() != tt.want {
+			t.Errorf("expected error '%s', got '%v'", tt.want, err)
+		}
+	}
+}